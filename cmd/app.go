@@ -2,12 +2,22 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
 
 	"golang.org/x/oauth2/google"
 
+	"github.com/ftomza/go-bank-bot/domain"
 	"github.com/ftomza/go-bank-bot/pkg/bot"
+	"github.com/ftomza/go-bank-bot/pkg/rates"
+	"github.com/ftomza/go-bank-bot/pkg/secret"
 	"github.com/ftomza/go-bank-bot/pkg/store"
 	"gopkg.in/tucnak/telebot.v2"
 	"gorm.io/driver/sqlite"
@@ -16,6 +26,15 @@ import (
 
 func main() {
 
+	if len(os.Args) > 1 && os.Args[1] == "generate-key" {
+		key, err := secret.GenerateKey()
+		if err != nil {
+			log.Fatalf("generate key: %v", err)
+		}
+		fmt.Println(key)
+		return
+	}
+
 	debug := os.Getenv("DEBUG")
 
 	db, err := gorm.Open(sqlite.Open("./data/app.db"), &gorm.Config{})
@@ -27,26 +46,118 @@ func main() {
 		db = db.Debug()
 	}
 
-	userRepo := store.NewGormUserRepository(db)
+	var userOpts []store.GormUserOption
+	keyring, err := secret.LoadKeyring(os.Getenv("MASTER_KEY"), os.Getenv("MASTER_KEY_FILE"))
+	if err == nil {
+		userOpts = append(userOpts, store.WithCipher(keyring))
+	} else if os.Getenv("MASTER_KEY") != "" || os.Getenv("MASTER_KEY_FILE") != "" {
+		log.Fatalf("load master key: %v", err)
+	} else {
+		log.Println("MASTER_KEY not set, google tokens are stored unencrypted")
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "rotate-keys" {
+		if keyring == nil {
+			log.Fatalf("rotate keys: MASTER_KEY not set")
+		}
+		count, err := store.RotateGormUserTokens(context.Background(), db, keyring)
+		if err != nil {
+			log.Fatalf("rotate keys: %v", err)
+		}
+		log.Printf("rotate keys: %d tokens encrypted with key %q", count, keyring.Primary())
+		return
+	}
+
+	userRepo := store.NewGormUserRepository(db, userOpts...)
 	err = userRepo.Migration(context.Background())
 	if err != nil {
 		log.Fatalf("migration db: %v", err)
 	}
 
+	ledgerRepo := store.NewGormTransactionRepository(db)
+	err = ledgerRepo.Migration(context.Background())
+	if err != nil {
+		log.Fatalf("migration db: %v", err)
+	}
+
+	outboxRepo := store.NewGormOutboxRepository(db)
+	err = outboxRepo.Migration(context.Background())
+	if err != nil {
+		log.Fatalf("migration db: %v", err)
+	}
+
+	fingerprintRepo := store.NewGormFingerprintRepository(db)
+	err = fingerprintRepo.Migration(context.Background())
+	if err != nil {
+		log.Fatalf("migration db: %v", err)
+	}
+
+	ruleRepo := store.NewGormRuleRepository(db)
+	err = ruleRepo.Migration(context.Background())
+	if err != nil {
+		log.Fatalf("migration db: %v", err)
+	}
+
+	categoryRepo := store.NewGormCategoryRepository(db)
+	err = categoryRepo.Migration(context.Background())
+	if err != nil {
+		log.Fatalf("migration db: %v", err)
+	}
+
+	budgetRepo := store.NewGormBudgetRepository(db)
+	err = budgetRepo.Migration(context.Background())
+	if err != nil {
+		log.Fatalf("migration db: %v", err)
+	}
+
+	balanceRepo := store.NewGormBalanceRepository(db)
+	err = balanceRepo.Migration(context.Background())
+	if err != nil {
+		log.Fatalf("migration db: %v", err)
+	}
+
+	rateRepo := store.NewGormRateRepository(db)
+	err = rateRepo.Migration(context.Background())
+	if err != nil {
+		log.Fatalf("migration db: %v", err)
+	}
+
+	var rateProviders []domain.RatesProvider
+	if v := os.Getenv("RATES_FILE"); v != "" {
+		fileRates, err := rates.NewFileProvider(v)
+		if err != nil {
+			log.Fatalf("load rates file: %v", err)
+		}
+		rateProviders = append(rateProviders, fileRates)
+	}
+	if v := os.Getenv("RATES_URL"); v != "" {
+		rateProviders = append(rateProviders, rates.NewHTTPProvider(v, &http.Client{Timeout: 10 * time.Second}))
+	}
+
 	cred := os.Getenv("CREDENTIALS")
 	if cred == "" {
 		log.Fatalf("CREDENTIALS not set")
 	}
 
-	config, err := google.ConfigFromJSON([]byte(os.Getenv("CREDENTIALS")),
+	scopes := []string{
 		"https://www.googleapis.com/auth/drive",
 		"https://www.googleapis.com/auth/drive.file",
 		"https://www.googleapis.com/auth/spreadsheets",
-	)
+	}
+
+	config, err := google.ConfigFromJSON([]byte(os.Getenv("CREDENTIALS")), scopes...)
 	if err != nil {
 		log.Fatalf("Unable to parse client secret file to config: %v", err)
 	}
 
+	serviceAccountJSON := []byte(os.Getenv("SERVICE_ACCOUNT"))
+	if v := os.Getenv("SERVICE_ACCOUNT_FILE"); v != "" {
+		serviceAccountJSON, err = ioutil.ReadFile(v)
+		if err != nil {
+			log.Fatalf("read service account file: %v", err)
+		}
+	}
+
 	token := os.Getenv("TOKEN")
 	if cred == "" {
 		log.Fatalf("TOKEN not set")
@@ -58,7 +169,58 @@ func main() {
 		Verbose: debug != "",
 	})
 
-	b := bot.NewTelegramBot(tb, userRepo, store.NewGoogleClient(config))
+	sinks := []string{bot.SinkGoogle, bot.SinkLedger}
+	if v := os.Getenv("SINKS"); v != "" {
+		sinks = nil
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				sinks = append(sinks, name)
+			}
+		}
+	}
+
+	opts := []bot.Option{
+		bot.WithLedger(ledgerRepo),
+		bot.WithOutbox(outboxRepo),
+		bot.WithFingerprints(fingerprintRepo),
+		bot.WithRules(ruleRepo),
+		bot.WithCategories(categoryRepo),
+		bot.WithBudgets(budgetRepo),
+		bot.WithBalances(balanceRepo),
+		bot.WithRates(rates.NewStoreProvider(rateRepo, rates.NewChainProvider(rateProviders...))),
+		bot.WithSinks(sinks...),
+	}
+	if len(serviceAccountJSON) > 0 {
+		account, err := store.NewGoogleServiceAccount(serviceAccountJSON, scopes...)
+		if err != nil {
+			log.Fatalf("Unable to parse service account: %v", err)
+		}
+		var allowed []int
+		for _, v := range strings.Split(os.Getenv("SERVICE_ACCOUNT_USERS"), ",") {
+			if v = strings.TrimSpace(v); v == "" {
+				continue
+			}
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				log.Fatalf("SERVICE_ACCOUNT_USERS: wrong telegram user id %q", v)
+			}
+			allowed = append(allowed, id)
+		}
+		if len(allowed) == 0 {
+			log.Println("SERVICE_ACCOUNT_USERS not set, service account mode is disabled")
+		}
+		log.Printf("google service account: %s", account.Email())
+		opts = append(opts, bot.WithServiceAccount(account, allowed...))
+	}
+	if v := os.Getenv("OAUTH_PUBLIC_URL"); v != "" {
+		addr := os.Getenv("OAUTH_LISTEN")
+		if addr == "" {
+			addr = ":8080"
+		}
+		opts = append(opts, bot.WithOAuthCallback(addr, v))
+	}
+
+	b := bot.NewTelegramBot(tb, userRepo, store.NewGoogleClient(config), opts...)
 
 	b.Start()
 }
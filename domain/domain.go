@@ -11,16 +11,47 @@ type User struct {
 	ID          uint         `json:"id"`
 	BotUserID   int          `json:"bot_user_id"`
 	TokSheet    []byte       `json:"tok_sheet"`
+	AuthMode    string       `json:"auth_mode"`
 	SheetID     string       `json:"sheet_id"`
 	ListName    string       `json:"list_name"`
+	MonthlyList bool         `json:"monthly_list"`
+	Formatted   bool         `json:"formatted"`
 	TrxPatterns []TrxPattern `json:"trx_patterns"`
+
+	AllowDuplicates bool          `json:"allow_duplicates"`
+	NumberFormat    *NumberFormat `json:"number_format"`
+	Timezone        string        `json:"timezone"`
+	BaseCurrency    string        `json:"base_currency"`
+
+	DigestSchedule string    `json:"digest_schedule"`
+	DigestTime     string    `json:"digest_time"`
+	LastDigestAt   time.Time `json:"last_digest_at"`
+}
+
+type NumberFormat struct {
+	Decimal string `json:"decimal"`
+	Group   string `json:"group"`
 }
 
 type TrxPattern struct {
-	Pattern string `json:"pattern"`
+	Pattern     string   `json:"pattern"`
+	Name        string   `json:"name,omitempty"`
+	DateLayouts []string `json:"date_layouts,omitempty"`
+	Currency    string   `json:"currency,omitempty"`
+	Direction   string   `json:"direction,omitempty"`
+	Account     string   `json:"account,omitempty"`
+	Income      []string `json:"income,omitempty"`
+	Expense     []string `json:"expense,omitempty"`
+
+	NumberFormat *NumberFormat `json:"number_format,omitempty"`
+
+	Preset  string `json:"preset,omitempty"`
+	Version int    `json:"version,omitempty"`
 }
 
 type Transaction struct {
+	ID        string
+	UserID    uint
 	Account   string
 	Party     string
 	Direction string
@@ -29,17 +60,166 @@ type Transaction struct {
 	Date      time.Time
 	Total     decimal.Decimal
 	Raw       string
+	Category  string
+	Tags      []string
+	Refs      map[string]string
+
+	BaseAmount   decimal.Decimal
+	BaseCurrency string
+}
+
+type TransactionFilter struct {
+	UserID    uint
+	From      time.Time
+	To        time.Time
+	Account   string
+	Category  string
+	Direction string
+	Text      string
+	Limit     int
+
+	NoBaseCurrency bool
+}
+
+type Rule struct {
+	ID         uint
+	UserID     uint
+	Party      string
+	PartyRegex string
+	MinAmount  decimal.NullDecimal
+	MaxAmount  decimal.NullDecimal
+	Account    string
+	Direction  string
+	Category   string
+	Tags       []string
+}
+
+type CategoryMapping struct {
+	UserID   uint
+	Party    string
+	Category string
+	Hits     int
+}
+
+type Budget struct {
+	ID         uint
+	UserID     uint
+	Category   string
+	Currency   string
+	Amount     decimal.Decimal
+	Thresholds []int
+}
+
+type Balance struct {
+	ID            uint
+	UserID        uint
+	Account       string
+	Currency      string
+	Amount        decimal.Decimal
+	Total         decimal.Decimal
+	Date          time.Time
+	TransactionID string
+}
+
+type Rate struct {
+	Base  string
+	Quote string
+	Date  time.Time
+	Rate  decimal.Decimal
+}
+
+type OutboxItem struct {
+	ID          uint
+	UserID      uint
+	Sink        string
+	Transaction Transaction
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+	Dead        bool
+	CreatedAt   time.Time
 }
 
 type UserRepository interface {
 	Get(ctx context.Context, id uint) (User, error)
 	GetByBotUserID(ctx context.Context, uid int) (User, error)
+	List(ctx context.Context) ([]User, error)
 	Store(ctx context.Context, user *User) error
 	Update(ctx context.Context, user *User) error
+	UpdateFields(ctx context.Context, user *User, fields ...string) error
 	Delete(ctx context.Context, user *User) error
 	Migration(ctx context.Context) error
 }
 
 type TransactionRepository interface {
 	Store(ctx context.Context, user *Transaction) error
+	Update(ctx context.Context, item *Transaction) error
+	Delete(ctx context.Context, item *Transaction) error
+	Get(ctx context.Context, userID uint, id string) (Transaction, error)
+	List(ctx context.Context, filter TransactionFilter) ([]Transaction, error)
+}
+
+type LedgerRepository interface {
+	TransactionRepository
+	Migration(ctx context.Context) error
+}
+
+type OutboxRepository interface {
+	Get(ctx context.Context, id uint) (OutboxItem, error)
+	ListByUser(ctx context.Context, userID uint) ([]OutboxItem, error)
+	ListDue(ctx context.Context, now time.Time, limit int) ([]OutboxItem, error)
+	Store(ctx context.Context, item *OutboxItem) error
+	Update(ctx context.Context, item *OutboxItem) error
+	Claim(ctx context.Context, item *OutboxItem, until time.Time) (bool, error)
+	Delete(ctx context.Context, item *OutboxItem) error
+	Migration(ctx context.Context) error
+}
+
+type FingerprintRepository interface {
+	Exists(ctx context.Context, userID uint, hash string) (bool, error)
+	Store(ctx context.Context, userID uint, hash string) error
+	Reserve(ctx context.Context, userID uint, hash string) (bool, error)
+	Delete(ctx context.Context, userID uint, hash string) error
+	Migration(ctx context.Context) error
+}
+
+type RuleRepository interface {
+	ListByUser(ctx context.Context, userID uint) ([]Rule, error)
+	Store(ctx context.Context, rule *Rule) error
+	Delete(ctx context.Context, rule *Rule) error
+	Migration(ctx context.Context) error
+}
+
+type CategoryRepository interface {
+	Get(ctx context.Context, userID uint, party string) (CategoryMapping, error)
+	ListByUser(ctx context.Context, userID uint) ([]CategoryMapping, error)
+	Store(ctx context.Context, mapping *CategoryMapping) error
+	Migration(ctx context.Context) error
+}
+
+type BudgetRepository interface {
+	ListByUser(ctx context.Context, userID uint) ([]Budget, error)
+	Store(ctx context.Context, budget *Budget) error
+	Delete(ctx context.Context, budget *Budget) error
+	AlertSent(ctx context.Context, budgetID uint, period string, threshold int) (bool, error)
+	StoreAlert(ctx context.Context, budgetID uint, period string, threshold int) error
+	Migration(ctx context.Context) error
+}
+
+type BalanceRepository interface {
+	Last(ctx context.Context, userID uint, account string) (Balance, error)
+	ListLatest(ctx context.Context, userID uint) ([]Balance, error)
+	Store(ctx context.Context, balance *Balance) error
+	DeleteByTransaction(ctx context.Context, userID uint, transactionID string) error
+	Migration(ctx context.Context) error
+}
+
+type RateRepository interface {
+	Get(ctx context.Context, base, quote string, date time.Time) (Rate, error)
+	Store(ctx context.Context, rate *Rate) error
+	Migration(ctx context.Context) error
+}
+
+type RatesProvider interface {
+	Rate(ctx context.Context, base, quote string, date time.Time) (decimal.Decimal, error)
 }
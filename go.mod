@@ -1,6 +1,6 @@
 module github.com/ftomza/go-bank-bot
 
-go 1.15
+go 1.16
 
 require (
 	github.com/DATA-DOG/go-sqlmock v1.5.0
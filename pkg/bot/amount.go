@@ -0,0 +1,139 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
+)
+
+var numberFormats = map[string]domain.NumberFormat{
+	"en": {Decimal: ".", Group: ","},
+	"de": {Decimal: ",", Group: "."},
+	"fr": {Decimal: ",", Group: " "},
+	"ru": {Decimal: ",", Group: " "},
+	"ch": {Decimal: ".", Group: "'"},
+}
+
+func parseNumberFormat(text string) (*domain.NumberFormat, error) {
+	if runes := []rune(text); len(runes) == 2 && runes[0] != runes[1] && isNumberSeparator(runes[0]) && isNumberSeparator(runes[1]) {
+		return &domain.NumberFormat{Decimal: string(runes[0]), Group: string(runes[1])}, nil
+	}
+	text = strings.TrimSpace(text)
+	if text == "" || strings.EqualFold(text, "auto") {
+		return nil, nil
+	}
+	if format, ok := numberFormats[strings.ToLower(text)]; ok {
+		return &format, nil
+	}
+	return nil, fmt.Errorf("unknown number format %q", text)
+}
+
+func isNumberSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func formatNumberFormat(format *domain.NumberFormat) string {
+	if format == nil {
+		return "auto"
+	}
+	for name, v := range numberFormats {
+		if v == *format && name != "fr" {
+			return name
+		}
+	}
+	return format.Decimal + format.Group
+}
+
+func isAmountSpace(r rune) bool {
+	return unicode.IsSpace(r) || r == ' ' || r == ' '
+}
+
+func isAmountGroup(r rune) bool {
+	return isAmountSpace(r) || r == '\'' || r == '’'
+}
+
+func parseAmount(text string, format *domain.NumberFormat) (decimal.Decimal, error) {
+	first := strings.IndexFunc(text, unicode.IsDigit)
+	last := strings.LastIndexFunc(text, unicode.IsDigit)
+	if first == -1 {
+		return decimal.Decimal{}, fmt.Errorf("can't convert %q to amount", text)
+	}
+	prefix, core, suffix := text[:first], text[first:last+1], text[last+1:]
+
+	negative := strings.ContainsAny(prefix, "-−–(") || strings.ContainsAny(suffix, "-−–")
+
+	var number string
+	var err error
+	if format != nil {
+		number, err = normalizeAmount(core, *format)
+	} else {
+		number, err = normalizeAutoAmount(core)
+	}
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("can't convert %q to amount: %w", text, err)
+	}
+
+	amount, err := decimal.NewFromString(number)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	if negative {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
+
+func normalizeAmount(core string, format domain.NumberFormat) (string, error) {
+	var b strings.Builder
+	for _, r := range core {
+		switch {
+		case unicode.IsDigit(r):
+			b.WriteRune(r)
+		case string(r) == format.Decimal:
+			b.WriteRune('.')
+		case string(r) == format.Group, format.Group == " " && isAmountSpace(r), format.Group == "'" && r == '’':
+		default:
+			return "", fmt.Errorf("unexpected %q", r)
+		}
+	}
+	if strings.Count(b.String(), ".") > 1 {
+		return "", errors.New("more than one decimal separator")
+	}
+	return b.String(), nil
+}
+
+func normalizeAutoAmount(core string) (string, error) {
+	var b strings.Builder
+	for _, r := range core {
+		switch {
+		case unicode.IsDigit(r), r == '.', r == ',':
+			b.WriteRune(r)
+		case isAmountGroup(r):
+		default:
+			return "", fmt.Errorf("unexpected %q", r)
+		}
+	}
+	number := b.String()
+
+	dot, comma := strings.LastIndex(number, "."), strings.LastIndex(number, ",")
+	switch {
+	case dot != -1 && comma != -1:
+		decimalSep, groupSep := ".", ","
+		if comma > dot {
+			decimalSep, groupSep = ",", "."
+		}
+		return normalizeAmount(number, domain.NumberFormat{Decimal: decimalSep, Group: groupSep})
+	case comma != -1:
+		if strings.Count(number, ",") > 1 || len(number)-comma-1 == 3 {
+			return strings.Replace(number, ",", "", -1), nil
+		}
+		return strings.Replace(number, ",", ".", 1), nil
+	case strings.Count(number, ".") > 1:
+		return strings.Replace(number, ".", "", -1), nil
+	}
+	return number, nil
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseAmount(t *testing.T) {
+	en := &domain.NumberFormat{Decimal: ".", Group: ","}
+	de := &domain.NumberFormat{Decimal: ",", Group: "."}
+	ru := &domain.NumberFormat{Decimal: ",", Group: " "}
+	ch := &domain.NumberFormat{Decimal: ".", Group: "'"}
+
+	tests := []struct {
+		name    string
+		text    string
+		format  *domain.NumberFormat
+		want    string
+		wantErr bool
+	}{
+		{name: "auto plain", text: "1123.33", want: "1123.33"},
+		{name: "auto en", text: "1,123.33", want: "1123.33"},
+		{name: "auto en thousands", text: "1,123", want: "1123"},
+		{name: "auto en millions", text: "1,234,567", want: "1234567"},
+		{name: "auto de", text: "1.234,56", want: "1234.56"},
+		{name: "auto de millions", text: "1.234.567", want: "1234567"},
+		{name: "auto decimal comma", text: "12,5", want: "12.5"},
+		{name: "auto ru", text: "1 234,56", want: "1234.56"},
+		{name: "auto ru nbsp", text: "1 234,56 ₽", want: "1234.56"},
+		{name: "auto ru narrow nbsp", text: "12 345,00 руб.", want: "12345"},
+		{name: "auto ch", text: "1'234.56", want: "1234.56"},
+		{name: "auto ch typographic", text: "CHF 1’234.56", want: "1234.56"},
+		{name: "auto currency prefix", text: "AED1,000.00", want: "1000"},
+		{name: "auto currency symbol", text: "$45.20", want: "45.2"},
+		{name: "auto leading minus", text: "-1 234,56", want: "-1234.56"},
+		{name: "auto unicode minus", text: "−500 ₽", want: "-500"},
+		{name: "auto trailing minus", text: "1.234,56-", want: "-1234.56"},
+		{name: "auto parentheses", text: "(1,234.56)", want: "-1234.56"},
+		{name: "auto plus", text: "+100.00 EUR", want: "100"},
+		{name: "en", text: "1,234.56", format: en, want: "1234.56"},
+		{name: "en three decimals", text: "1.234", format: en, want: "1.234"},
+		{name: "de", text: "1.234,56", format: de, want: "1234.56"},
+		{name: "de thousands", text: "1.234", format: de, want: "1234"},
+		{name: "de trailing minus", text: "12,00-", format: de, want: "-12"},
+		{name: "ru", text: "1 234 567,89", format: ru, want: "1234567.89"},
+		{name: "ru nbsp", text: "1 234,5 р.", format: ru, want: "1234.5"},
+		{name: "ch", text: "Fr. 1'234.50", format: ch, want: "1234.5"},
+		{name: "ch negative", text: "-1'234.50", format: ch, want: "-1234.5"},
+		{name: "fail wrong format", text: "1,234,56", format: de, wantErr: true},
+		{name: "fail empty", text: "", wantErr: true},
+		{name: "fail letters", text: "12a34", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAmount(tt.text, tt.format)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.True(t, decimal.RequireFromString(tt.want).Equal(got), "got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseNumberFormat(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    *domain.NumberFormat
+		wantErr bool
+	}{
+		{text: "auto"},
+		{text: ""},
+		{text: "DE", want: &domain.NumberFormat{Decimal: ",", Group: "."}},
+		{text: "ch", want: &domain.NumberFormat{Decimal: ".", Group: "'"}},
+		{text: ", ", want: &domain.NumberFormat{Decimal: ",", Group: " "}},
+		{text: "..", wantErr: true},
+		{text: "xx", wantErr: true},
+		{text: "1,", wantErr: true},
+		{text: ",1", wantErr: true},
+		{text: ".a", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseNumberFormat(tt.text)
+		if tt.wantErr {
+			assert.Error(t, err, tt.text)
+			continue
+		}
+		if assert.NoError(t, err, tt.text) {
+			assert.Equal(t, tt.want, got, tt.text)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/store"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var (
+	btnAuthMode = telebot.Btn{Unique: "authMode"}
+)
+
+var (
+	errServiceAccountNotConfigured = errors.New("service account not configured")
+	errServiceAccountNotAllowed    = errors.New("service account is not allowed for you")
+)
+
+func WithServiceAccount(account *store.GoogleServiceAccount, allowedUsers ...int) Option {
+	return func(tg *TelegramBot) {
+		tg.serviceAccount = account
+		tg.serviceAccountUsers = map[int]bool{}
+		for _, v := range allowedUsers {
+			tg.serviceAccountUsers[v] = true
+		}
+	}
+}
+
+func (tg *TelegramBot) checkServiceAccount(botUserID int) error {
+	if tg.serviceAccount == nil {
+		return errServiceAccountNotConfigured
+	}
+	if !tg.serviceAccountUsers[botUserID] {
+		return errServiceAccountNotAllowed
+	}
+	return nil
+}
+
+func (tg *TelegramBot) checkServiceAccountSheet(u domain.User, sheetID string) error {
+	if sheetID == "" {
+		return nil
+	}
+	users, err := tg.userRepo.List(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, v := range users {
+		if v.ID != u.ID && v.SheetID == sheetID && userAuthMode(v) == store.GoogleAuthServiceAccount {
+			return errors.New("sheet is already used by another user of the service account")
+		}
+	}
+	return nil
+}
+
+func userAuthMode(u domain.User) string {
+	return IfThenElse(u.AuthMode == "", store.GoogleAuthOAuth, u.AuthMode).(string)
+}
+
+func (tg *TelegramBot) formatAuthMode(u domain.User) string {
+	if userAuthMode(u) == store.GoogleAuthServiceAccount {
+		if tg.serviceAccount == nil {
+			return "service account (not configured)"
+		}
+		return "service account " + tg.serviceAccount.Email()
+	}
+	return "personal account"
+}
+
+func (tg *TelegramBot) googleHTTPClient(u domain.User) (*http.Client, error) {
+	switch userAuthMode(u) {
+	case store.GoogleAuthServiceAccount:
+		if err := tg.checkServiceAccount(u.BotUserID); err != nil {
+			return nil, err
+		}
+		return tg.serviceAccount.Client(context.Background()), nil
+	case store.GoogleAuthOAuth:
+		if u.TokSheet == nil {
+			return nil, errors.New("google token not set")
+		}
+		return tg.trxClient.Get(context.Background(), u.TokSheet,
+			store.WithTokenSave(func(token []byte) error {
+				return tg.SaveRepoUserGoogleToken(u.BotUserID, token)
+			}),
+			store.WithTokenRevoked(func(err error) {
+				tg.revokeRepoUserGoogleToken(u, err)
+			}),
+		)
+	}
+	return nil, fmt.Errorf("unknown auth mode %q", u.AuthMode)
+}
+
+func (tg *TelegramBot) SaveRepoUserAuthMode(userID int, mode string) error {
+	switch mode {
+	case store.GoogleAuthOAuth:
+	case store.GoogleAuthServiceAccount:
+		if err := tg.checkServiceAccount(userID); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown auth mode %q", mode)
+	}
+	return tg.wrapperRepoUser(userID, func(u domain.User) error {
+		if mode == store.GoogleAuthServiceAccount {
+			if err := tg.checkServiceAccountSheet(u, u.SheetID); err != nil {
+				return err
+			}
+		}
+		u.AuthMode = mode
+		return tg.userRepo.UpdateFields(context.Background(), &u, "AuthMode")
+	})
+}
+
+func (tg *TelegramBot) authModeHandler(_ telegramBotCommand, m *telebot.Message) {
+	_ = tg.wrapperErr(m, func() error {
+		user, err := tg.GetOrCreateRepoUser(m.Sender.ID)
+		if err != nil {
+			return err
+		}
+		selector := &telebot.ReplyMarkup{}
+		rows := []telebot.Row{selector.Row(selector.Data("Personal account", btnAuthMode.Unique, store.GoogleAuthOAuth))}
+		if tg.checkServiceAccount(m.Sender.ID) == nil {
+			rows = append(rows, selector.Row(selector.Data("Service account", btnAuthMode.Unique, store.GoogleAuthServiceAccount)))
+		}
+		selector.Inline(rows...)
+		return tg.Send(m.Sender, fmt.Sprintf("Google auth: %s\nPlease choose mode:", tg.formatAuthMode(user)), selector)
+	})
+}
+
+func (tg *TelegramBot) authModeCallback(c *telebot.Callback) {
+	_ = tg.bot.Respond(c)
+	m := &telebot.Message{Sender: c.Sender}
+	_ = tg.wrapperErr(m, func() error {
+		if err := tg.SaveRepoUserAuthMode(c.Sender.ID, c.Data); err != nil {
+			return err
+		}
+		if c.Data == store.GoogleAuthServiceAccount {
+			return tg.Send(c.Sender, fmt.Sprintf(
+				"Google auth: service account ✔\n\nPlease share your spreadsheet with %s as Editor and set it with /%s.",
+				tg.serviceAccount.Email(), setSheetCommand.Command))
+		}
+		return tg.Send(c.Sender, fmt.Sprintf("Google auth: personal account ✔\n\nSend /%s if the token is not set yet.", addGoogleTokenCommand.Command))
+	})
+}
@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func Test_SaveRepoUserAuthMode(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:bot_auth?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	require.NoError(t, err)
+	users := store.NewGormUserRepository(db)
+	require.NoError(t, users.Migration(context.Background()))
+	require.NoError(t, users.Store(context.Background(), &domain.User{BotUserID: 42}))
+
+	tg := &TelegramBot{userRepo: users}
+	user, err := tg.GetRepoUser(42)
+	require.NoError(t, err)
+	assert.Equal(t, "personal account", tg.formatAuthMode(user))
+	_, err = tg.googleHTTPClient(user)
+	assert.EqualError(t, err, "google token not set")
+
+	assert.EqualError(t, tg.SaveRepoUserAuthMode(42, store.GoogleAuthServiceAccount), "service account not configured")
+	assert.Error(t, tg.SaveRepoUserAuthMode(42, "unknown"))
+
+	account, err := store.NewGoogleServiceAccount([]byte(`{"type":"service_account","client_email":"bot@project.iam.gserviceaccount.com","private_key":"key"}`))
+	require.NoError(t, err)
+	WithServiceAccount(account, 42, 43)(tg)
+	assert.Equal(t, errServiceAccountNotAllowed, tg.SaveRepoUserAuthMode(44, store.GoogleAuthServiceAccount))
+	require.NoError(t, tg.SaveRepoUserSheet(42, "shared"))
+	require.NoError(t, tg.SaveRepoUserAuthMode(42, store.GoogleAuthServiceAccount))
+
+	require.NoError(t, tg.SaveRepoUserAuthMode(43, store.GoogleAuthServiceAccount))
+	assert.Error(t, tg.SaveRepoUserSheet(43, "shared"), "sheet of another user")
+	assert.NoError(t, tg.SaveRepoUserSheet(43, "own"))
+
+	user, err = tg.GetRepoUser(42)
+	require.NoError(t, err)
+	assert.Equal(t, store.GoogleAuthServiceAccount, user.AuthMode)
+	assert.Equal(t, "service account bot@project.iam.gserviceaccount.com", tg.formatAuthMode(user))
+	client, err := tg.googleHTTPClient(user)
+	assert.NoError(t, err)
+	assert.NotNil(t, client)
+
+	delete(tg.serviceAccountUsers, 42)
+	_, err = tg.googleHTTPClient(user)
+	assert.Equal(t, errServiceAccountNotAllowed, err)
+
+	tg.serviceAccount = nil
+	_, err = tg.googleHTTPClient(user)
+	assert.EqualError(t, err, "service account not configured")
+	assert.Equal(t, "service account (not configured)", tg.formatAuthMode(user))
+}
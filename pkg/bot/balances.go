@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
+	"gopkg.in/tucnak/telebot.v2"
+	"gorm.io/gorm"
+)
+
+func signedAmount(trans domain.Transaction) decimal.Decimal {
+	if isExpense(trans) {
+		return trans.Amount.Abs().Neg()
+	}
+	return trans.Amount.Abs()
+}
+
+func expectedBalance(previous domain.Balance, trans domain.Transaction) decimal.Decimal {
+	return previous.Total.Add(signedAmount(trans))
+}
+
+func formatBalanceMismatch(previous domain.Balance, trans domain.Transaction) string {
+	amount := signedAmount(trans)
+	sign := IfThenElse(amount.IsNegative(), "-", "+").(string)
+	return strings.TrimSpace(fmt.Sprintf("⚠️ Balance of account %s: expected %s (%s %s %s), bank reports %s. A message may have been missed.",
+		valueOrDefault(trans.Account, "unknown"),
+		strings.TrimSpace(expectedBalance(previous, trans).StringFixed(2)+" "+trans.Currency),
+		previous.Total.StringFixed(2), sign, amount.Abs().StringFixed(2),
+		strings.TrimSpace(trans.Total.StringFixed(2)+" "+trans.Currency)))
+}
+
+func formatBalances(u domain.User, items []domain.Balance) string {
+	var lines []string
+	for _, v := range items {
+		lines = append(lines, fmt.Sprintf("%s: %s (%s)",
+			valueOrDefault(v.Account, "unknown"),
+			strings.TrimSpace(v.Total.StringFixed(2)+" "+v.Currency),
+			v.Date.In(userLocation(u)).Format("02/01/2006 15:04")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (tg *TelegramBot) trackBalance(u domain.User, trans *domain.Transaction) (string, error) {
+	if tg.balances == nil || trans.Total.IsZero() {
+		return "", nil
+	}
+	ctx := context.Background()
+	previous, err := tg.balances.Last(ctx, u.ID, trans.Account)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	found := err == nil
+
+	err = tg.balances.Store(ctx, &domain.Balance{
+		UserID:        u.ID,
+		Account:       trans.Account,
+		Currency:      trans.Currency,
+		Amount:        trans.Amount,
+		Total:         trans.Total,
+		Date:          trans.Date,
+		TransactionID: trans.ID,
+	})
+	if err != nil || !found || trans.Date.Before(previous.Date) {
+		return "", err
+	}
+	if !strings.EqualFold(previous.Currency, trans.Currency) || expectedBalance(previous, *trans).Equal(trans.Total) {
+		return "", nil
+	}
+	return formatBalanceMismatch(previous, *trans), nil
+}
+
+func (tg *TelegramBot) balanceHandler(_ telegramBotCommand, m *telebot.Message) {
+	_ = tg.wrapperErr(m, func() error {
+		if tg.balances == nil {
+			return errors.New("balances not configured")
+		}
+		user, err := tg.GetRepoUser(m.Sender.ID)
+		if err != nil {
+			return err
+		}
+		items, err := tg.balances.ListLatest(context.Background(), user.ID)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return tg.Send(m.Sender, "No balances yet. Balances are tracked from messages with a total.")
+		}
+		return tg.Send(m.Sender, "Last known balances:\n\n"+formatBalances(user, items))
+	})
+}
@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/store"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func Test_expectedBalance(t *testing.T) {
+	previous := domain.Balance{Total: decimal.RequireFromString("1000")}
+	tests := []struct {
+		name  string
+		trans domain.Transaction
+		want  string
+	}{
+		{name: "negative debit", trans: domain.Transaction{Amount: decimal.RequireFromString("-20.5")}, want: "979.5"},
+		{name: "positive debit", trans: domain.Transaction{Amount: decimal.RequireFromString("20.5"), Direction: "debit"}, want: "1020.5"},
+		{name: "credit", trans: domain.Transaction{Amount: decimal.RequireFromString("100"), Direction: "credit"}, want: "1100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := expectedBalance(previous, tt.trans)
+			assert.True(t, decimal.RequireFromString(tt.want).Equal(got), got.String())
+		})
+	}
+}
+
+func Test_trackBalance(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:bot_balance?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	require.NoError(t, err)
+	balances := store.NewGormBalanceRepository(db)
+	require.NoError(t, balances.Migration(context.Background()))
+
+	tg := &TelegramBot{balances: balances}
+	u := domain.User{ID: 1}
+	date := func(day int) time.Time {
+		return time.Date(2020, 11, day, 10, 0, 0, 0, time.UTC)
+	}
+	trans := func(id string, day int, amount, total string) *domain.Transaction {
+		return &domain.Transaction{
+			ID: id, UserID: 1, Account: "5098", Direction: "debit", Currency: "AED", Date: date(day),
+			Amount: decimal.RequireFromString(amount), Total: decimal.RequireFromString(total),
+		}
+	}
+	tests := []struct {
+		name  string
+		trans *domain.Transaction
+		want  string
+	}{
+		{name: "first", trans: trans("t1", 1, "-20", "1000")},
+		{name: "matches", trans: trans("t2", 2, "-20", "980")},
+		{name: "no total", trans: trans("t3", 3, "-20", "0")},
+		{name: "missed message", trans: trans("t4", 4, "-10", "950"),
+			want: "⚠️ Balance of account 5098: expected 970.00 AED (980.00 - 10.00), bank reports 950.00 AED. A message may have been missed."},
+		{name: "out of order", trans: trans("t5", 1, "-500", "10")},
+		{name: "after missed", trans: trans("t6", 5, "-50", "900")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tg.trackBalance(u, tt.trans)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	items, err := balances.ListLatest(context.Background(), 1)
+	if assert.NoError(t, err) && assert.Len(t, items, 1) {
+		assert.Equal(t, "t6", items[0].TransactionID)
+		assert.Equal(t, "5098: 900.00 AED (05/11/2020 10:00)", formatBalances(u, items))
+	}
+}
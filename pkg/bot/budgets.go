@@ -0,0 +1,302 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var (
+	btnBudgetAdd    = telebot.Btn{Unique: "budgetAdd"}
+	btnBudgetDelete = telebot.Btn{Unique: "budgetDelete"}
+
+	budgetDefaultThresholds = []int{80, 100}
+)
+
+func isExpense(trans domain.Transaction) bool {
+	return trans.Amount.IsNegative()
+}
+
+func budgetMatches(budget domain.Budget, trans domain.Transaction) bool {
+	return budget.Category == "" || strings.EqualFold(budget.Category, trans.Category)
+}
+
+func budgetCurrency(budget domain.Budget, u domain.User) string {
+	return strings.ToUpper(valueOrDefault(budget.Currency, u.BaseCurrency))
+}
+
+func budgetAmount(currency string, trans domain.Transaction) (decimal.Decimal, bool) {
+	switch {
+	case currency == "":
+		return decimal.Zero, false
+	case strings.EqualFold(currency, trans.Currency):
+		return trans.Amount, true
+	case strings.EqualFold(currency, trans.BaseCurrency):
+		return trans.BaseAmount, true
+	}
+	return decimal.Zero, false
+}
+
+func budgetSpent(budget domain.Budget, items []domain.Transaction) decimal.Decimal {
+	spent := decimal.Zero
+	for _, v := range items {
+		if !budgetMatches(budget, v) || !isExpense(v) {
+			continue
+		}
+		if amount, ok := budgetAmount(budget.Currency, v); ok {
+			spent = spent.Add(amount.Abs())
+		}
+	}
+	return spent
+}
+
+func budgetCrossed(budget domain.Budget, spent decimal.Decimal) []int {
+	if !budget.Amount.IsPositive() {
+		return nil
+	}
+	percent := spent.Mul(decimal.NewFromInt(100)).Div(budget.Amount)
+	var crossed []int
+	for _, v := range budget.Thresholds {
+		if percent.GreaterThanOrEqual(decimal.NewFromInt(int64(v))) {
+			crossed = append(crossed, v)
+		}
+	}
+	sort.Ints(crossed)
+	return crossed
+}
+
+func budgetPeriod(date time.Time) (string, time.Time, time.Time) {
+	from := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+	return from.Format("2006-01"), from, from.AddDate(0, 1, 0)
+}
+
+func budgetName(budget domain.Budget) string {
+	return IfThenElse(budget.Category == "", "Overall", budget.Category).(string)
+}
+
+func formatBudgetAmount(budget domain.Budget) string {
+	return strings.TrimSpace(budget.Amount.StringFixed(2) + " " + budget.Currency)
+}
+
+func formatBudget(budget domain.Budget) string {
+	var thresholds []string
+	for _, v := range budget.Thresholds {
+		thresholds = append(thresholds, fmt.Sprintf("%d%%", v))
+	}
+	return fmt.Sprintf("%s: %s, alerts at %s", budgetName(budget), formatBudgetAmount(budget), strings.Join(thresholds, ", "))
+}
+
+func formatBudgetAlert(budget domain.Budget, spent decimal.Decimal, threshold int) string {
+	percent := spent.Mul(decimal.NewFromInt(100)).Div(budget.Amount).StringFixed(0)
+	icon := IfThenElse(threshold >= 100, "🚫", "⚠️").(string)
+	return fmt.Sprintf("%s Budget %s: spent %s of %s (%s%%) this month.",
+		icon, budgetName(budget), spent.StringFixed(2), formatBudgetAmount(budget), percent)
+}
+
+func parseBudget(budget *domain.Budget, settings string, format *domain.NumberFormat) error {
+	for i, line := range strings.Split(settings, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("line %d: expected key=value", i+1)
+		}
+		key, value := strings.ToLower(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1])
+		switch key {
+		case "category":
+			budget.Category = value
+		case "amount":
+			for _, v := range strings.Fields(value) {
+				if strings.IndexFunc(v, unicode.IsDigit) == -1 {
+					budget.Currency = strings.ToUpper(v)
+				}
+			}
+			amount, err := parseAmount(value, format)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", i+1, err)
+			}
+			budget.Amount = amount.Abs()
+		case "currency":
+			budget.Currency = strings.ToUpper(value)
+		case "thresholds":
+			budget.Thresholds = nil
+			for _, v := range strings.Split(value, ",") {
+				v = strings.TrimSuffix(strings.TrimSpace(v), "%")
+				if v == "" {
+					continue
+				}
+				threshold, err := strconv.Atoi(v)
+				if err != nil || threshold <= 0 {
+					return fmt.Errorf("line %d: wrong threshold %q", i+1, v)
+				}
+				budget.Thresholds = append(budget.Thresholds, threshold)
+			}
+			sort.Ints(budget.Thresholds)
+		default:
+			return fmt.Errorf("line %d: unknown setting %q", i+1, key)
+		}
+	}
+	if !budget.Amount.IsPositive() {
+		return errors.New("budget amount required")
+	}
+	if len(budget.Thresholds) == 0 {
+		budget.Thresholds = budgetDefaultThresholds
+	}
+	return nil
+}
+
+func (tg *TelegramBot) checkBudgets(u domain.User, trans *domain.Transaction) ([]string, error) {
+	if tg.budgets == nil || !isExpense(*trans) {
+		return nil, nil
+	}
+	ctx := context.Background()
+	budgets, err := tg.budgets.ListByUser(ctx, u.ID)
+	if err != nil || len(budgets) == 0 {
+		return nil, err
+	}
+	repo, err := tg.userQueryRepo(u)
+	if err != nil {
+		return nil, err
+	}
+	period, from, to := budgetPeriod(trans.Date.In(userLocation(u)))
+	items, err := repo.List(ctx, domain.TransactionFilter{UserID: u.ID, From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []string
+	for _, budget := range budgets {
+		budget.Currency = budgetCurrency(budget, u)
+		if !budgetMatches(budget, *trans) {
+			continue
+		}
+		if _, ok := budgetAmount(budget.Currency, *trans); !ok {
+			continue
+		}
+		spent := budgetSpent(budget, items)
+		last := 0
+		for _, threshold := range budgetCrossed(budget, spent) {
+			sent, err := tg.budgets.AlertSent(ctx, budget.ID, period, threshold)
+			if err != nil {
+				return alerts, err
+			}
+			if sent {
+				continue
+			}
+			if err := tg.budgets.StoreAlert(ctx, budget.ID, period, threshold); err != nil {
+				return alerts, err
+			}
+			last = threshold
+		}
+		if last > 0 {
+			alerts = append(alerts, formatBudgetAlert(budget, spent, last))
+		}
+	}
+	return alerts, nil
+}
+
+func (tg *TelegramBot) AddRepoUserBudget(userID int, settings string) (domain.Budget, error) {
+	budget := domain.Budget{}
+	if tg.budgets == nil {
+		return budget, errors.New("budgets not configured")
+	}
+	err := tg.wrapperRepoUser(userID, func(u domain.User) error {
+		if err := parseBudget(&budget, settings, u.NumberFormat); err != nil {
+			return err
+		}
+		if budgetCurrency(budget, u) == "" {
+			return errors.New("budget currency required: set currency or the base currency")
+		}
+		budget.UserID = u.ID
+		return tg.budgets.Store(context.Background(), &budget)
+	})
+	return budget, err
+}
+
+func (tg *TelegramBot) DeleteRepoUserBudget(userID int, budgetID uint) error {
+	if tg.budgets == nil {
+		return errors.New("budgets not configured")
+	}
+	return tg.wrapperRepoUser(userID, func(u domain.User) error {
+		return tg.budgets.Delete(context.Background(), &domain.Budget{ID: budgetID, UserID: u.ID})
+	})
+}
+
+func (tg *TelegramBot) budgetHandler(_ telegramBotCommand, m *telebot.Message) {
+	_ = tg.wrapperErr(m, func() error {
+		if tg.budgets == nil {
+			return errors.New("budgets not configured")
+		}
+		user, err := tg.GetRepoUser(m.Sender.ID)
+		if err != nil {
+			return err
+		}
+		budgets, err := tg.budgets.ListByUser(context.Background(), user.ID)
+		if err != nil {
+			return err
+		}
+
+		selector := &telebot.ReplyMarkup{}
+		rows := []telebot.Row{selector.Row(selector.Data("Add budget", btnBudgetAdd.Unique))}
+		var lines []string
+		for _, v := range budgets {
+			id := strconv.FormatUint(uint64(v.ID), 10)
+			lines = append(lines, fmt.Sprintf("#%s %s", id, formatBudget(v)))
+			rows = append(rows, selector.Row(selector.Data("Delete #"+id, btnBudgetDelete.Unique, id)))
+		}
+		selector.Inline(rows...)
+
+		if len(lines) == 0 {
+			return tg.Send(m.Sender, "Budgets not set.", selector)
+		}
+		return tg.Send(m.Sender, "Monthly budgets:\n\n"+strings.Join(lines, "\n"), selector)
+	})
+}
+
+func (tg *TelegramBot) budgetAddCallback(c *telebot.Callback) {
+	_ = tg.bot.Respond(c)
+	m := &telebot.Message{Sender: c.Sender}
+	_ = tg.Send(c.Sender, "Please send monthly budget as key=value lines:\n\n"+
+		"category=Food\namount=1500 AED\nthresholds=80, 100\n\n"+
+		"Empty category sets the overall budget.")
+
+	tg.wrapperSession(m, budgetCommand.Name, func(cancel context.CancelFunc) Step {
+		return NewStep(func(ctx context.Context, sess *Session) error {
+			defer cancel()
+			return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+				return tg.wrapperErr(msg, func() error {
+					budget, err := tg.AddRepoUserBudget(msg.Sender.ID, strings.TrimSpace(msg.Text))
+					if err != nil {
+						return err
+					}
+					return tg.Send(msg.Sender, fmt.Sprintf("Budget #%d: ✔\n\n%s", budget.ID, formatBudget(budget)))
+				})
+			})
+		})
+	})
+}
+
+func (tg *TelegramBot) budgetDeleteCallback(c *telebot.Callback) {
+	_ = tg.bot.Respond(c)
+	m := &telebot.Message{Sender: c.Sender}
+	_ = tg.wrapperErr(m, func() error {
+		id, err := strconv.ParseUint(c.Data, 10, 64)
+		if err != nil {
+			return err
+		}
+		if err := tg.DeleteRepoUserBudget(c.Sender.ID, uint(id)); err != nil {
+			return err
+		}
+		return tg.Send(c.Sender, fmt.Sprintf("Budget #%d deleted.", id))
+	})
+}
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		want     domain.Budget
+		wantErr  bool
+	}{
+		{name: "category", settings: "category=Food\namount=1,500.50 aed\nthresholds=50%, 90, 100", want: domain.Budget{
+			Category: "Food", Currency: "AED", Amount: decimal.RequireFromString("1500.5"), Thresholds: []int{50, 90, 100},
+		}},
+		{name: "overall default thresholds", settings: "amount=3000\ncurrency=usd", want: domain.Budget{
+			Currency: "USD", Amount: decimal.RequireFromString("3000"), Thresholds: []int{80, 100},
+		}},
+		{name: "no amount", settings: "category=Food", wantErr: true},
+		{name: "wrong threshold", settings: "amount=10\nthresholds=80,x", wantErr: true},
+		{name: "unknown key", settings: "amount=10\nlimit=5", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := domain.Budget{}
+			err := parseBudget(&got, tt.settings, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.Category, got.Category)
+			assert.Equal(t, tt.want.Currency, got.Currency)
+			assert.True(t, tt.want.Amount.Equal(got.Amount), got.Amount.String())
+			assert.Equal(t, tt.want.Thresholds, got.Thresholds)
+		})
+	}
+}
+
+func Test_isExpense(t *testing.T) {
+	tests := []struct {
+		name  string
+		trans domain.Transaction
+		want  bool
+	}{
+		{name: "negative", trans: domain.Transaction{Amount: decimal.RequireFromString("-10"), Direction: "Покупка"}, want: true},
+		{name: "positive", trans: domain.Transaction{Amount: decimal.RequireFromString("10"), Direction: "Пополнение"}, want: false},
+		{name: "positive debit", trans: domain.Transaction{Amount: decimal.RequireFromString("10"), Direction: "Debit"}, want: false},
+		{name: "zero", trans: domain.Transaction{Amount: decimal.Zero}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isExpense(tt.trans))
+		})
+	}
+}
+
+func Test_budgetSpent(t *testing.T) {
+	items := []domain.Transaction{
+		{Category: "Food", Currency: "AED", Amount: decimal.RequireFromString("-100")},
+		{Category: "food", Currency: "AED", Amount: decimal.RequireFromString("-50")},
+		{Category: "Food", Currency: "USD", Amount: decimal.RequireFromString("-20"), BaseCurrency: "AED", BaseAmount: decimal.RequireFromString("-73.46")},
+		{Category: "Food", Currency: "EUR", Amount: decimal.RequireFromString("-10")},
+		{Category: "Transport", Currency: "AED", Amount: decimal.RequireFromString("-30")},
+		{Category: "Food", Currency: "AED", Amount: decimal.RequireFromString("40")},
+	}
+	tests := []struct {
+		name   string
+		budget domain.Budget
+		want   string
+	}{
+		{name: "category", budget: domain.Budget{Category: "FOOD", Currency: "AED"}, want: "223.46"},
+		{name: "overall", budget: domain.Budget{Currency: "AED"}, want: "253.46"},
+		{name: "other currency", budget: domain.Budget{Category: "Food", Currency: "USD"}, want: "20"},
+		{name: "no currency", budget: domain.Budget{Category: "Food"}, want: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := budgetSpent(tt.budget, items)
+			assert.True(t, decimal.RequireFromString(tt.want).Equal(got), got.String())
+		})
+	}
+}
+
+func Test_budgetCrossed(t *testing.T) {
+	budget := domain.Budget{Amount: decimal.RequireFromString("1000"), Thresholds: []int{80, 100}}
+	tests := []struct {
+		spent string
+		want  []int
+	}{
+		{spent: "799.99"},
+		{spent: "800", want: []int{80}},
+		{spent: "1200", want: []int{80, 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spent, func(t *testing.T) {
+			assert.Equal(t, tt.want, budgetCrossed(budget, decimal.RequireFromString(tt.spent)))
+		})
+	}
+	assert.Nil(t, budgetCrossed(domain.Budget{Thresholds: []int{80}}, decimal.RequireFromString("10")))
+}
+
+func Test_budgetPeriod(t *testing.T) {
+	loc := time.FixedZone("+04", 4*60*60)
+	key, from, to := budgetPeriod(time.Date(2020, 12, 31, 23, 0, 0, 0, loc))
+	assert.Equal(t, "2020-12", key)
+	assert.Equal(t, time.Date(2020, 12, 1, 0, 0, 0, 0, loc), from)
+	assert.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, loc), to)
+}
+
+func Test_formatBudgetAlert(t *testing.T) {
+	budget := domain.Budget{Category: "Food", Currency: "AED", Amount: decimal.RequireFromString("1000"), Thresholds: []int{80, 100}}
+	assert.Equal(t, "⚠️ Budget Food: spent 850.00 of 1000.00 AED (85%) this month.",
+		formatBudgetAlert(budget, decimal.RequireFromString("850"), 80))
+	assert.Equal(t, "🚫 Budget Overall: spent 1200.00 of 1000.00 (120%) this month.",
+		formatBudgetAlert(domain.Budget{Amount: budget.Amount}, decimal.RequireFromString("1200"), 100))
+	assert.Equal(t, "Food: 1000.00 AED, alerts at 80%, 100%", formatBudget(budget))
+}
@@ -0,0 +1,178 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"gopkg.in/tucnak/telebot.v2"
+	"gorm.io/gorm"
+)
+
+const (
+	categorySuggestionsLimit = 6
+	categoryOther            = "-"
+)
+
+var (
+	btnCategory = telebot.Btn{Unique: "category"}
+)
+
+func categoryParty(party string) string {
+	return strings.Join(strings.Fields(strings.ToLower(party)), " ")
+}
+
+func suggestCategories(trans *domain.Transaction, learned []domain.CategoryMapping, rules []domain.Rule) []string {
+	var result []string
+	add := func(category string) {
+		if category == "" || len(result) >= categorySuggestionsLimit {
+			return
+		}
+		for _, v := range result {
+			if strings.EqualFold(v, category) {
+				return
+			}
+		}
+		result = append(result, category)
+	}
+	add(trans.Category)
+	party := categoryParty(trans.Party)
+	for _, v := range learned {
+		if v.Party == party {
+			add(v.Category)
+		}
+	}
+	for _, v := range rules {
+		if ruleMatches(v, trans) {
+			add(v.Category)
+		}
+	}
+	return result
+}
+
+func (tg *TelegramBot) applyLearnedCategory(u domain.User, trans *domain.Transaction) error {
+	if tg.categories == nil || trans.Party == "" || trans.Category != "" {
+		return nil
+	}
+	mapping, err := tg.categories.Get(context.Background(), u.ID, categoryParty(trans.Party))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	trans.Category = mapping.Category
+	return nil
+}
+
+func (tg *TelegramBot) suggestUserCategories(u domain.User, trans *domain.Transaction) ([]string, error) {
+	if tg.categories == nil || trans.Party == "" {
+		return nil, nil
+	}
+	ctx := context.Background()
+	learned, err := tg.categories.ListByUser(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	var rules []domain.Rule
+	if tg.rules != nil {
+		if rules, err = tg.rules.ListByUser(ctx, u.ID); err != nil {
+			return nil, err
+		}
+	}
+	return suggestCategories(trans, learned, rules), nil
+}
+
+func (tg *TelegramBot) LearnRepoUserCategory(userID int, party string, category string) error {
+	if tg.categories == nil {
+		return errors.New("categories not configured")
+	}
+	category = strings.TrimSpace(category)
+	if category == "" {
+		return errors.New("category is empty")
+	}
+	return tg.wrapperRepoUser(userID, func(u domain.User) error {
+		return tg.categories.Store(context.Background(), &domain.CategoryMapping{
+			UserID:   u.ID,
+			Party:    categoryParty(party),
+			Category: category,
+		})
+	})
+}
+
+func (tg *TelegramBot) categoryRows(selector *telebot.ReplyMarkup, id string, categories []string) []telebot.Row {
+	var rows []telebot.Row
+	var buttons []telebot.Btn
+	for i, v := range categories {
+		buttons = append(buttons, selector.Data(v, btnCategory.Unique, id, strconv.Itoa(i)))
+		if len(buttons) == 2 {
+			rows = append(rows, selector.Row(buttons...))
+			buttons = nil
+		}
+	}
+	buttons = append(buttons, selector.Data("Other…", btnCategory.Unique, id, categoryOther))
+	return append(rows, selector.Row(buttons...))
+}
+
+func (tg *TelegramBot) categoryCallback(c *telebot.Callback) {
+	_ = tg.bot.Respond(c)
+	m := &telebot.Message{Sender: c.Sender}
+	_ = tg.wrapperErr(m, func() error {
+		parts := strings.SplitN(c.Data, "|", 2)
+		if len(parts) != 2 {
+			return errors.New("wrong category choice")
+		}
+		_, item, err := tg.getRecentTransaction(c.Sender.ID, parts[0])
+		if err != nil {
+			return err
+		}
+
+		if parts[1] == categoryOther {
+			_ = tg.Send(c.Sender, fmt.Sprintf("Please send category for %q:", item.Transaction.Party))
+			tg.wrapperSession(m, "Category", func(cancel context.CancelFunc) Step {
+				return NewStep(func(ctx context.Context, sess *Session) error {
+					defer cancel()
+					return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+						return tg.wrapperErr(msg, func() error {
+							return tg.chooseCategory(msg.Sender, item, strings.TrimSpace(msg.Text))
+						})
+					})
+				})
+			})
+			return nil
+		}
+
+		index, err := strconv.Atoi(parts[1])
+		if err != nil || index < 0 || index >= len(item.Categories) {
+			return errors.New("wrong category choice")
+		}
+		return tg.chooseCategory(c.Sender, item, item.Categories[index])
+	})
+}
+
+func (tg *TelegramBot) chooseCategory(to *telebot.User, item recentTransaction, category string) error {
+	trans := item.Transaction
+	if err := tg.LearnRepoUserCategory(to.ID, trans.Party, category); err != nil {
+		return err
+	}
+	txt := fmt.Sprintf("Category %q learned for %q. ✔", category, trans.Party)
+	var alerts []string
+	if trans.Category != category {
+		trans.Category = category
+		results, budgetAlerts, err := tg.UpdateSavedTransaction(to.ID, item, &trans)
+		if err != nil {
+			return err
+		}
+		txt += formatSinkResults(results)
+		alerts = budgetAlerts
+	}
+	if err := tg.Send(to, txt); err != nil {
+		return err
+	}
+	for _, v := range alerts {
+		_ = tg.Send(to, v)
+	}
+	return nil
+}
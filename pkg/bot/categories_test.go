@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func Test_suggestCategories(t *testing.T) {
+	learned := []domain.CategoryMapping{
+		{Party: "uber trip", Category: "Transport"},
+		{Party: "cafe", Category: "Food"},
+		{Party: "taxi", Category: "transport"},
+	}
+	rules := []domain.Rule{
+		{Party: "uber", Category: "Bills"},
+		{Party: "uber", Tags: []string{"work"}},
+		{Party: "cafe", Category: "Coffee"},
+	}
+	uber := &domain.Transaction{Party: "UBER  Trip"}
+	assert.Equal(t, []string{"Transport", "Bills"}, suggestCategories(uber, learned, rules))
+	uber.Category = "Taxi"
+	assert.Equal(t, []string{"Taxi", "Transport", "Bills"}, suggestCategories(uber, learned, rules))
+	assert.Nil(t, suggestCategories(&domain.Transaction{Party: "shop"}, learned, rules))
+}
+
+func Test_categoryParty(t *testing.T) {
+	assert.Equal(t, "uber trip help.uber.com", categoryParty("  UBER   Trip\tHELP.UBER.COM "))
+}
+
+func Test_applyLearnedCategory_afterRules(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open("file:bot_categories?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	require.NoError(t, err)
+	categories := store.NewGormCategoryRepository(db)
+	require.NoError(t, categories.Migration(ctx))
+	require.NoError(t, categories.Store(ctx, &domain.CategoryMapping{UserID: 1, Party: "uber trip", Category: "Food"}))
+
+	tg := &TelegramBot{categories: categories}
+	u := domain.User{ID: 1}
+
+	ruled := &domain.Transaction{Party: "UBER TRIP"}
+	applyRules([]domain.Rule{{Party: "uber", Category: "Transport"}}, ruled)
+	require.NoError(t, tg.applyLearnedCategory(u, ruled))
+	assert.Equal(t, "Transport", ruled.Category, "rule wins over learned mapping")
+
+	learned := &domain.Transaction{Party: "UBER TRIP"}
+	require.NoError(t, tg.applyLearnedCategory(u, learned))
+	assert.Equal(t, "Food", learned.Category)
+}
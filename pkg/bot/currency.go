@@ -0,0 +1,139 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/rates"
+	"github.com/shopspring/decimal"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	baseCurrencyOff       = "off"
+	ratesTimeout          = 30 * time.Second
+	ratesBackfillInterval = time.Hour
+)
+
+var currencyCodeRx = regexp.MustCompile(`^[A-Z]{3}$`)
+
+func parseBaseCurrency(text string) (string, error) {
+	if strings.EqualFold(strings.TrimSpace(text), baseCurrencyOff) {
+		return "", nil
+	}
+	code := rates.NormalizeCurrency(text)
+	if !currencyCodeRx.MatchString(code) {
+		return "", fmt.Errorf("wrong currency %q, expected ISO code like USD", text)
+	}
+	return code, nil
+}
+
+func (tg *TelegramBot) applyBaseCurrency(u domain.User, trans *domain.Transaction) error {
+	trans.BaseAmount, trans.BaseCurrency = decimal.Decimal{}, ""
+	if tg.rates == nil || u.BaseCurrency == "" || trans.Currency == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ratesTimeout)
+	defer cancel()
+	amount, err := rates.Convert(ctx, tg.rates, trans.Amount, trans.Currency, u.BaseCurrency, trans.Date)
+	if err != nil {
+		return err
+	}
+	trans.BaseAmount, trans.BaseCurrency = amount, u.BaseCurrency
+	return nil
+}
+
+func (tg *TelegramBot) runRatesBackfill(ctx context.Context) {
+	ticker := time.NewTicker(ratesBackfillInterval)
+	defer ticker.Stop()
+	for {
+		tg.backfillBaseCurrency(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (tg *TelegramBot) backfillBaseCurrency(ctx context.Context) {
+	users, err := tg.userRepo.List(ctx)
+	if err != nil {
+		log.Println("rates backfill list users: ", err)
+		return
+	}
+	for _, u := range users {
+		if ctx.Err() != nil {
+			return
+		}
+		if u.BaseCurrency == "" {
+			continue
+		}
+		items, err := tg.ledger.List(ctx, domain.TransactionFilter{UserID: u.ID, NoBaseCurrency: true})
+		if err != nil {
+			log.Println("rates backfill list transactions: ", err)
+			continue
+		}
+		for _, v := range items {
+			trans := v
+			if trans.Currency == "" {
+				continue
+			}
+			if err := tg.applyBaseCurrency(u, &trans); err != nil {
+				log.Println("rates backfill convert: ", err)
+				continue
+			}
+			if err := tg.ledger.Update(ctx, &trans); err != nil {
+				log.Println("rates backfill update: ", err)
+			}
+		}
+	}
+}
+
+func toBaseCurrency(items []domain.Transaction, base string) []domain.Transaction {
+	if base == "" {
+		return items
+	}
+	result := make([]domain.Transaction, 0, len(items))
+	for _, v := range items {
+		if v.BaseCurrency == base {
+			v.Amount, v.Currency = v.BaseAmount, v.BaseCurrency
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+func (tg *TelegramBot) SaveRepoUserBaseCurrency(userID int, text string) (string, error) {
+	code, err := parseBaseCurrency(text)
+	if err != nil {
+		return "", err
+	}
+	return code, tg.wrapperRepoUser(userID, func(u domain.User) error {
+		u.BaseCurrency = code
+		return tg.userRepo.UpdateFields(context.Background(), &u, "BaseCurrency")
+	})
+}
+
+func (tg *TelegramBot) baseCurrencyHandler(c telegramBotCommand, m *telebot.Message) {
+	_ = tg.Send(m.Sender, fmt.Sprintf("Please set base currency for reports, e.g. USD, EUR, $ or %q to disable conversion", baseCurrencyOff))
+	tg.wrapperSession(m, c.Name, func(cancel context.CancelFunc) Step {
+		return NewStep(func(ctx context.Context, sess *Session) error {
+			defer cancel()
+			return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+				return tg.wrapperErr(msg, func() error {
+					code, err := tg.SaveRepoUserBaseCurrency(msg.Sender.ID, msg.Text)
+					if err != nil {
+						return err
+					}
+					return tg.Send(msg.Sender, fmt.Sprintf("Base currency: %s ✔", IfThenElse(code == "", baseCurrencyOff, code)))
+				})
+			})
+		})
+	})
+}
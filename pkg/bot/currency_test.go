@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/rates"
+	"github.com/ftomza/go-bank-bot/pkg/store"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type fixedRates map[string]decimal.Decimal
+
+func (r fixedRates) Rate(_ context.Context, base, quote string, _ time.Time) (decimal.Decimal, error) {
+	if rate, ok := r[base+"/"+quote]; ok {
+		return rate, nil
+	}
+	return decimal.Decimal{}, rates.ErrRateNotFound
+}
+
+func Test_parseBaseCurrency(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{text: "usd", want: "USD"},
+		{text: "$", want: "USD"},
+		{text: "руб.", want: "RUB"},
+		{text: "Off", want: ""},
+		{text: "dollars", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := parseBaseCurrency(tt.text)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_applyBaseCurrency(t *testing.T) {
+	tg := &TelegramBot{rates: fixedRates{"EUR/USD": decimal.RequireFromString("1.2")}}
+	user := domain.User{BaseCurrency: "USD"}
+	tests := []struct {
+		name     string
+		user     domain.User
+		trans    domain.Transaction
+		want     string
+		currency string
+		wantErr  bool
+	}{
+		{name: "convert", user: user, trans: domain.Transaction{Amount: decimal.RequireFromString("-10.25"), Currency: "EUR"}, want: "-12.3", currency: "USD"},
+		{name: "same", user: user, trans: domain.Transaction{Amount: decimal.RequireFromString("5"), Currency: "USD"}, want: "5", currency: "USD"},
+		{name: "no base", trans: domain.Transaction{Amount: decimal.RequireFromString("5"), Currency: "EUR"}, want: "0"},
+		{name: "no rate", user: user, trans: domain.Transaction{Amount: decimal.RequireFromString("5"), Currency: "GBP"}, want: "0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trans := tt.trans
+			err := tg.applyBaseCurrency(tt.user, &trans)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.True(t, decimal.RequireFromString(tt.want).Equal(trans.BaseAmount), trans.BaseAmount.String())
+			assert.Equal(t, tt.currency, trans.BaseCurrency)
+		})
+	}
+}
+
+func Test_toBaseCurrency(t *testing.T) {
+	items := []domain.Transaction{
+		{Amount: decimal.RequireFromString("-10"), Currency: "EUR", BaseAmount: decimal.RequireFromString("-12"), BaseCurrency: "USD"},
+		{Amount: decimal.RequireFromString("-5"), Currency: "GBP"},
+		{Amount: decimal.RequireFromString("-3"), Currency: "EUR", BaseAmount: decimal.RequireFromString("-20"), BaseCurrency: "AED"},
+	}
+	r := buildReport(reportPeriod{}, toBaseCurrency(items, "USD"), nil)
+	assert.Equal(t, "-3.00 EUR, -5.00 GBP, -12.00 USD", formatCurrencyTotals(r.Totals))
+	assert.Equal(t, "EUR", items[0].Currency, "source items untouched")
+	assert.Equal(t, items, toBaseCurrency(items, ""))
+}
+
+func Test_backfillBaseCurrency(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open("file:bot_rates_backfill?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	require.NoError(t, err)
+	users := store.NewGormUserRepository(db)
+	require.NoError(t, users.Migration(ctx))
+	ledger := store.NewGormTransactionRepository(db)
+	require.NoError(t, ledger.Migration(ctx))
+
+	require.NoError(t, users.Store(ctx, &domain.User{BotUserID: 42, BaseCurrency: "USD"}))
+	user, err := users.GetByBotUserID(ctx, 42)
+	require.NoError(t, err)
+	date := time.Date(2020, 11, 1, 10, 0, 0, 0, time.UTC)
+	items := []domain.Transaction{
+		{ID: "t1", UserID: user.ID, Amount: decimal.RequireFromString("-10"), Currency: "EUR", Date: date},
+		{ID: "t2", UserID: user.ID, Amount: decimal.RequireFromString("-5"), Currency: "GBP", Date: date},
+	}
+	for i := range items {
+		require.NoError(t, ledger.Store(ctx, &items[i]))
+	}
+
+	tg := &TelegramBot{userRepo: users, ledger: ledger, rates: fixedRates{"EUR/USD": decimal.RequireFromString("1.2")}}
+	tg.backfillBaseCurrency(ctx)
+
+	got, err := ledger.Get(ctx, user.ID, "t1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "USD", got.BaseCurrency)
+		assert.True(t, decimal.RequireFromString("-12").Equal(got.BaseAmount), got.BaseAmount.String())
+	}
+	got, err = ledger.Get(ctx, user.ID, "t2")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "", got.BaseCurrency, "rate still missing")
+	}
+}
@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeNow = time.Now
+
+var isoDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+}
+
+var dateLayouts = []string{
+	"2/1/2006 15:04:05", "2/1/2006 15:04", "2/1/2006", "2/1/06 15:04", "2/1/06", "2/1 15:04", "2/1",
+	"2.1.2006 15:04:05", "2.1.2006 15:04", "2.1.2006", "2.1.06 15:04", "2.1.06", "2.1 15:04", "2.1",
+	"2006-1-2 15:04:05", "2006-1-2 15:04", "2006-1-2", "2-1-2006 15:04", "2-1-2006",
+	"2006/1/2 15:04:05", "2006/1/2 15:04", "2006/1/2",
+	"2 Jan 2006 15:04:05", "2 Jan 2006 15:04", "2 Jan 2006", "2 Jan 06", "2 Jan 15:04", "2 Jan",
+	"Jan 2 2006 15:04", "Jan 2 2006", "Jan 2 15:04", "Jan 2",
+	"15:04:05", "15:04",
+}
+
+var monthNames = map[string][]string{
+	"Jan": {"january", "jan", "январь", "января", "янв", "januar", "jänner", "janvier", "janv", "enero", "ene"},
+	"Feb": {"february", "feb", "февраль", "февраля", "фев", "februar", "février", "févr", "fév", "febrero"},
+	"Mar": {"march", "mar", "март", "марта", "мар", "märz", "mär", "mrz", "mars", "marzo"},
+	"Apr": {"april", "apr", "апрель", "апреля", "апр", "avril", "avr", "abril", "abr"},
+	"May": {"may", "май", "мая", "mai", "mayo"},
+	"Jun": {"june", "jun", "июнь", "июня", "июн", "juni", "juin", "junio"},
+	"Jul": {"july", "jul", "июль", "июля", "июл", "juli", "juillet", "juil", "julio"},
+	"Aug": {"august", "aug", "август", "августа", "авг", "août", "aoû", "agosto", "ago"},
+	"Sep": {"september", "sep", "sept", "сентябрь", "сентября", "сен", "сент", "septembre", "septiembre"},
+	"Oct": {"october", "oct", "октябрь", "октября", "окт", "oktober", "okt", "octobre", "octubre"},
+	"Nov": {"november", "nov", "ноябрь", "ноября", "ноя", "novembre", "noviembre"},
+	"Dec": {"december", "dec", "декабрь", "декабря", "дек", "dezember", "dez", "décembre", "déc", "diciembre", "dic"},
+}
+
+var (
+	monthLookup = newMonthLookup()
+	dateWordRx  = regexp.MustCompile(`\p{L}+\.?`)
+	dateSpaceRx = regexp.MustCompile(`[\s,.\-]*\s[\s,.\-]*`)
+	tzOffsetRx  = regexp.MustCompile(`^(?:UTC|GMT)?\s*([+-])(\d{1,2})(?::?(\d{2}))?$`)
+)
+
+func newMonthLookup() map[string]string {
+	lookup := map[string]string{}
+	for month, names := range monthNames {
+		for _, v := range names {
+			lookup[v] = month
+		}
+	}
+	return lookup
+}
+
+func normalizeDateText(text string) string {
+	text = dateWordRx.ReplaceAllStringFunc(text, func(word string) string {
+		if month, ok := monthLookup[strings.ToLower(strings.TrimSuffix(word, "."))]; ok {
+			return " " + month + " "
+		}
+		return " "
+	})
+	return strings.TrimSpace(dateSpaceRx.ReplaceAllString(" "+text+" ", " "))
+}
+
+func layoutFields(layout string) (hasDate, hasYear bool) {
+	ref := time.Date(1999, 7, 30, 0, 0, 0, 0, time.UTC)
+	parsed, err := time.Parse(layout, ref.Format(layout))
+	if err != nil {
+		return true, true
+	}
+	return parsed.Day() == 30, parsed.Year() == 1999
+}
+
+func completeDate(date time.Time, layout string, now time.Time) time.Time {
+	hasDate, hasYear := layoutFields(layout)
+	switch {
+	case !hasDate:
+		date = time.Date(now.Year(), now.Month(), now.Day(),
+			date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+		if date.After(now.Add(time.Hour)) {
+			date = date.AddDate(0, 0, -1)
+		}
+	case !hasYear:
+		date = time.Date(now.Year(), date.Month(), date.Day(),
+			date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+		if date.After(now.AddDate(0, 0, 1)) {
+			date = date.AddDate(-1, 0, 0)
+		}
+	}
+	return date
+}
+
+func parseDateLayouts(text string, layouts []string, loc *time.Location, now time.Time) (time.Time, bool) {
+	for _, layout := range layouts {
+		if date, err := time.ParseInLocation(layout, text, loc); err == nil {
+			return completeDate(date, layout, now), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func parseDate(text string, layouts []string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	now := timeNow().In(loc)
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return now, nil
+	}
+
+	if len(layouts) > 0 {
+		if date, ok := parseDateLayouts(text, layouts, loc, now); ok {
+			return date, nil
+		}
+		if date, ok := parseDateLayouts(normalizeDateText(text), layouts, loc, now); ok {
+			return date, nil
+		}
+		return time.Time{}, fmt.Errorf("date %q does not match layouts: %s", text, strings.Join(layouts, ", "))
+	}
+
+	if date, ok := parseDateLayouts(text, isoDateLayouts, loc, now); ok {
+		return date, nil
+	}
+	if date, ok := parseDateLayouts(normalizeDateText(text), dateLayouts, loc, now); ok {
+		return date, nil
+	}
+	return time.Time{}, fmt.Errorf("can't parse date %q", text)
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.UTC, nil
+	}
+	if match := tzOffsetRx.FindStringSubmatch(name); match != nil {
+		hours, _ := strconv.Atoi(match[2])
+		minutes, _ := strconv.Atoi(match[3])
+		offset := hours*3600 + minutes*60
+		if match[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(name, offset), nil
+	}
+	return time.LoadLocation(name)
+}
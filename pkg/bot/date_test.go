@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseDate(t *testing.T) {
+	defer func(fn func() time.Time) { timeNow = fn }(timeNow)
+	timeNow = func() time.Time { return time.Date(2021, 1, 3, 10, 0, 0, 0, time.UTC) }
+
+	dubai := time.FixedZone("+04:00", 4*3600)
+
+	tests := []struct {
+		name    string
+		text    string
+		layouts []string
+		loc     *time.Location
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "empty", text: "", want: time.Date(2021, 1, 3, 10, 0, 0, 0, time.UTC)},
+		{name: "empty in location", text: "", loc: dubai, want: time.Date(2021, 1, 3, 14, 0, 0, 0, dubai)},
+		{name: "dd/mm/yyyy", text: "31/10/2020", want: time.Date(2020, 10, 31, 0, 0, 0, 0, time.UTC)},
+		{name: "d/m/yyyy", text: "5/1/2021", want: time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{name: "dd/mm this year", text: "02/01", want: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "dd/mm december in january", text: "31/12", want: time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{name: "dd.mm.yy", text: "05.11.20", want: time.Date(2020, 11, 5, 0, 0, 0, 0, time.UTC)},
+		{name: "dd.mm.yyyy time", text: "05.11.2020 14:32", want: time.Date(2020, 11, 5, 14, 32, 0, 0, time.UTC)},
+		{name: "dd/mm/yyyy time seconds", text: "05/11/2020 12:01:10", want: time.Date(2020, 11, 5, 12, 1, 10, 0, time.UTC)},
+		{name: "iso", text: "2020-11-05", want: time.Date(2020, 11, 5, 0, 0, 0, 0, time.UTC)},
+		{name: "iso time", text: "2020-11-05T14:32:00", want: time.Date(2020, 11, 5, 14, 32, 0, 0, time.UTC)},
+		{name: "rfc3339", text: "2020-11-05T14:32:00+03:00", want: time.Date(2020, 11, 5, 11, 32, 0, 0, time.UTC)},
+		{name: "english month", text: "5 November 2020", want: time.Date(2020, 11, 5, 0, 0, 0, 0, time.UTC)},
+		{name: "english month first", text: "Nov 5, 2020 at 14:32", want: time.Date(2020, 11, 5, 14, 32, 0, 0, time.UTC)},
+		{name: "english dashes", text: "05-Nov-2020", want: time.Date(2020, 11, 5, 0, 0, 0, 0, time.UTC)},
+		{name: "russian month", text: "5 октября 2020 г. в 14:32", want: time.Date(2020, 10, 5, 14, 32, 0, 0, time.UTC)},
+		{name: "russian short month", text: "28 дек.", want: time.Date(2020, 12, 28, 0, 0, 0, 0, time.UTC)},
+		{name: "german month", text: "3. März 2020", want: time.Date(2020, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{name: "french month", text: "1 février 2020", want: time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "time only", text: "09:15", want: time.Date(2021, 1, 3, 9, 15, 0, 0, time.UTC)},
+		{name: "time only yesterday", text: "23:50", want: time.Date(2021, 1, 2, 23, 50, 0, 0, time.UTC)},
+		{name: "time in location", text: "13:30", loc: dubai, want: time.Date(2021, 1, 3, 13, 30, 0, 0, dubai)},
+		{name: "date in location", text: "31/12/2020 23:30", loc: dubai, want: time.Date(2020, 12, 31, 23, 30, 0, 0, dubai)},
+		{name: "layouts", text: "2020|11|05", layouts: []string{"2006|01|02"}, want: time.Date(2020, 11, 5, 0, 0, 0, 0, time.UTC)},
+		{name: "layouts without year", text: "12-30", layouts: []string{"01-02"}, want: time.Date(2020, 12, 30, 0, 0, 0, 0, time.UTC)},
+		{name: "layouts month name", text: "5 окт 2020", layouts: []string{"2 Jan 2006"}, want: time.Date(2020, 10, 5, 0, 0, 0, 0, time.UTC)},
+		{name: "fail layouts", text: "05/11/2020", layouts: []string{"2006|01|02"}, wantErr: true},
+		{name: "fail", text: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDate(tt.text, tt.layouts, tt.loc)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.True(t, tt.want.Equal(got), "got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_loadLocation(t *testing.T) {
+	loc, err := loadLocation("")
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	loc, err = loadLocation("UTC+03:30")
+	if assert.NoError(t, err) {
+		_, offset := time.Date(2020, 1, 1, 0, 0, 0, 0, loc).Zone()
+		assert.Equal(t, 3*3600+30*60, offset)
+	}
+
+	loc, err = loadLocation("-5")
+	if assert.NoError(t, err) {
+		_, offset := time.Date(2020, 1, 1, 0, 0, 0, 0, loc).Zone()
+		assert.Equal(t, -5*3600, offset)
+	}
+
+	loc, err = loadLocation("Europe/Moscow")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Europe/Moscow", loc.String())
+	}
+
+	_, err = loadLocation("Mars/Olympus")
+	assert.Error(t, err)
+}
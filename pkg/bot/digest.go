@@ -0,0 +1,233 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	digestOff     = "off"
+	digestDaily   = "daily"
+	digestWeekly  = "weekly"
+	digestMonthly = "monthly"
+
+	digestDefaultTime = "09:00"
+	digestInterval    = time.Minute
+)
+
+var (
+	btnDigest = telebot.Btn{Unique: "digest"}
+
+	digestTimeRx = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?$`)
+)
+
+func parseDigestTime(text string) (string, error) {
+	match := digestTimeRx.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return "", fmt.Errorf("wrong time %q, expected HH:MM", text)
+	}
+	hour, _ := strconv.Atoi(match[1])
+	minute, _ := strconv.Atoi(match[2])
+	if hour > 23 || minute > 59 {
+		return "", fmt.Errorf("wrong time %q, expected HH:MM", text)
+	}
+	return fmt.Sprintf("%02d:%02d", hour, minute), nil
+}
+
+func digestSlot(schedule string, at string, now time.Time) (time.Time, error) {
+	at, err := parseDigestTime(IfThenElse(at == "", digestDefaultTime, at).(string))
+	if err != nil {
+		return time.Time{}, err
+	}
+	hour, _ := strconv.Atoi(at[:2])
+	minute, _ := strconv.Atoi(at[3:])
+	slot := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	switch schedule {
+	case digestDaily:
+		if slot.After(now) {
+			slot = slot.AddDate(0, 0, -1)
+		}
+	case digestWeekly:
+		slot = slot.AddDate(0, 0, -((int(slot.Weekday()) + 6) % 7))
+		if slot.After(now) {
+			slot = slot.AddDate(0, 0, -7)
+		}
+	case digestMonthly:
+		slot = slot.AddDate(0, 0, 1-slot.Day())
+		if slot.After(now) {
+			slot = slot.AddDate(0, -1, 0)
+		}
+	default:
+		return time.Time{}, fmt.Errorf("unknown digest schedule %q", schedule)
+	}
+	return slot, nil
+}
+
+func digestPeriod(schedule string, slot time.Time) reportPeriod {
+	to := time.Date(slot.Year(), slot.Month(), slot.Day(), 0, 0, 0, 0, slot.Location())
+	switch schedule {
+	case digestWeekly:
+		return reportPeriod{Name: reportWeek, From: to.AddDate(0, 0, -7), To: to}
+	case digestMonthly:
+		return reportPeriod{Name: reportMonth, From: to.AddDate(0, -1, 0), To: to}
+	}
+	return reportPeriod{Name: reportCustom, From: to.AddDate(0, 0, -1), To: to}
+}
+
+func digestDue(u domain.User, now time.Time) (time.Time, bool) {
+	if u.DigestSchedule == "" {
+		return time.Time{}, false
+	}
+	slot, err := digestSlot(u.DigestSchedule, u.DigestTime, now.In(userLocation(u)))
+	if err != nil {
+		log.Println("digest slot: ", err)
+		return time.Time{}, false
+	}
+	return slot, slot.After(u.LastDigestAt)
+}
+
+func formatDigestSettings(u domain.User) string {
+	if u.DigestSchedule == "" {
+		return "off"
+	}
+	return u.DigestSchedule + " at " + IfThenElse(u.DigestTime == "", digestDefaultTime, u.DigestTime).(string)
+}
+
+func (tg *TelegramBot) runDigests(ctx context.Context) {
+	ticker := time.NewTicker(digestInterval)
+	defer ticker.Stop()
+	for {
+		tg.sendDueDigests(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (tg *TelegramBot) sendDueDigests(ctx context.Context) {
+	users, err := tg.userRepo.List(ctx)
+	if err != nil {
+		log.Println("digest list users: ", err)
+		return
+	}
+	now := timeNow()
+	for _, v := range users {
+		if ctx.Err() != nil {
+			return
+		}
+		slot, ok := digestDue(v, now)
+		if !ok {
+			continue
+		}
+		if err := tg.sendDigest(ctx, v, slot); err != nil {
+			log.Println("digest send: ", err)
+		}
+	}
+}
+
+func (tg *TelegramBot) sendDigest(ctx context.Context, u domain.User, slot time.Time) error {
+	user, err := tg.userRepo.Get(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	user.LastDigestAt = timeNow()
+	if err := tg.userRepo.UpdateFields(ctx, &user, "LastDigestAt"); err != nil {
+		return err
+	}
+
+	r, err := tg.buildUserReport(u, digestPeriod(u.DigestSchedule, slot))
+	if err != nil {
+		return err
+	}
+	title := strings.ToUpper(u.DigestSchedule[:1]) + u.DigestSchedule[1:]
+	txt := fmt.Sprintf("*%s digest*\n\n", EscapeMarkdown2(title)) + formatReport(r)
+	return tg.Send(&telebot.User{ID: u.BotUserID}, txt, telebot.ModeMarkdownV2)
+}
+
+func (tg *TelegramBot) SaveRepoUserDigest(userID int, schedule string, at string) error {
+	if schedule == digestOff {
+		schedule, at = "", ""
+	}
+	if schedule != "" {
+		var err error
+		if at, err = parseDigestTime(at); err != nil {
+			return err
+		}
+		if _, err = digestSlot(schedule, at, timeNow()); err != nil {
+			return err
+		}
+	}
+	return tg.wrapperRepoUser(userID, func(u domain.User) error {
+		u.DigestSchedule = schedule
+		u.DigestTime = at
+		u.LastDigestAt = timeNow()
+		return tg.userRepo.UpdateFields(context.Background(), &u, "DigestSchedule", "DigestTime", "LastDigestAt")
+	})
+}
+
+func (tg *TelegramBot) digestHandler(_ telegramBotCommand, m *telebot.Message) {
+	_ = tg.wrapperErr(m, func() error {
+		user, err := tg.GetOrCreateRepoUser(m.Sender.ID)
+		if err != nil {
+			return err
+		}
+		selector := &telebot.ReplyMarkup{}
+		selector.Inline(
+			selector.Row(
+				selector.Data("Daily", btnDigest.Unique, digestDaily),
+				selector.Data("Weekly", btnDigest.Unique, digestWeekly),
+				selector.Data("Monthly", btnDigest.Unique, digestMonthly),
+			),
+			selector.Row(selector.Data("Off", btnDigest.Unique, digestOff)),
+		)
+		return tg.Send(m.Sender, fmt.Sprintf("Digest: %s\nPlease choose schedule:", formatDigestSettings(user)), selector)
+	})
+}
+
+func (tg *TelegramBot) digestCallback(c *telebot.Callback) {
+	_ = tg.bot.Respond(c)
+	m := &telebot.Message{Sender: c.Sender}
+	schedule := c.Data
+	if schedule == digestOff {
+		_ = tg.wrapperErr(m, func() error {
+			if err := tg.SaveRepoUserDigest(c.Sender.ID, digestOff, ""); err != nil {
+				return err
+			}
+			return tg.Send(c.Sender, "Digest: off")
+		})
+		return
+	}
+
+	_ = tg.Send(c.Sender, fmt.Sprintf("Please send local time of %s digest as HH:MM or %q for %s:", schedule, editKeep, digestDefaultTime))
+	tg.wrapperSession(m, digestCommand.Name, func(cancel context.CancelFunc) Step {
+		return NewStep(func(ctx context.Context, sess *Session) error {
+			defer cancel()
+			return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+				return tg.wrapperErr(msg, func() error {
+					at := strings.TrimSpace(msg.Text)
+					if at == editKeep {
+						at = digestDefaultTime
+					}
+					if err := tg.SaveRepoUserDigest(msg.Sender.ID, schedule, at); err != nil {
+						return err
+					}
+					user, err := tg.GetRepoUser(msg.Sender.ID)
+					if err != nil {
+						return err
+					}
+					return tg.Send(msg.Sender, "Digest: "+formatDigestSettings(user))
+				})
+			})
+		})
+	})
+}
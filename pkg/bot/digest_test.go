@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseDigestTime(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{text: "9", want: "09:00"},
+		{text: " 21:05 ", want: "21:05"},
+		{text: "24:00", wantErr: true},
+		{text: "9am", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := parseDigestTime(tt.text)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_digestSlot(t *testing.T) {
+	loc := time.FixedZone("+04", 4*60*60)
+	// Thursday
+	now := time.Date(2020, 11, 12, 8, 0, 0, 0, loc)
+	tests := []struct {
+		schedule string
+		at       string
+		want     time.Time
+		wantErr  bool
+	}{
+		{schedule: digestDaily, at: "07:30", want: time.Date(2020, 11, 12, 7, 30, 0, 0, loc)},
+		{schedule: digestDaily, at: "", want: time.Date(2020, 11, 11, 9, 0, 0, 0, loc)},
+		{schedule: digestWeekly, at: "09:00", want: time.Date(2020, 11, 9, 9, 0, 0, 0, loc)},
+		{schedule: digestMonthly, at: "09:00", want: time.Date(2020, 11, 1, 9, 0, 0, 0, loc)},
+		{schedule: "hourly", at: "09:00", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schedule+" "+tt.at, func(t *testing.T) {
+			got, err := digestSlot(tt.schedule, tt.at, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	got, err := digestSlot(digestMonthly, "09:00", time.Date(2020, 11, 1, 8, 0, 0, 0, loc))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 10, 1, 9, 0, 0, 0, loc), got)
+}
+
+func Test_digestPeriod(t *testing.T) {
+	slot := time.Date(2020, 11, 9, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, "08/11/2020 - 08/11/2020", digestPeriod(digestDaily, slot).String())
+	assert.Equal(t, "02/11/2020 - 08/11/2020", digestPeriod(digestWeekly, slot).String())
+	assert.Equal(t, "09/10/2020 - 08/11/2020", digestPeriod(digestMonthly, slot).String())
+	assert.Equal(t, "01/10/2020 - 31/10/2020", digestPeriod(digestMonthly, time.Date(2020, 11, 1, 9, 0, 0, 0, time.UTC)).String())
+}
+
+func Test_digestDue(t *testing.T) {
+	now := time.Date(2020, 11, 12, 6, 0, 0, 0, time.UTC)
+	user := domain.User{DigestSchedule: digestDaily, DigestTime: "09:00", Timezone: "+04:00"}
+
+	user.LastDigestAt = time.Date(2020, 11, 11, 5, 0, 0, 0, time.UTC)
+	slot, ok := digestDue(user, now)
+	assert.True(t, ok)
+	assert.True(t, slot.Equal(time.Date(2020, 11, 12, 5, 0, 0, 0, time.UTC)))
+
+	user.LastDigestAt = time.Date(2020, 11, 12, 5, 0, 1, 0, time.UTC)
+	_, ok = digestDue(user, now)
+	assert.False(t, ok)
+
+	_, ok = digestDue(domain.User{}, now)
+	assert.False(t, ok)
+}
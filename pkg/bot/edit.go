@@ -0,0 +1,395 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/store"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	recentTransactionTTL = 24 * time.Hour
+	editKeep             = "-"
+)
+
+var (
+	btnTransactionEdit = telebot.Btn{Unique: "trxEdit"}
+	btnTransactionUndo = telebot.Btn{Unique: "trxUndo"}
+)
+
+type recentTransaction struct {
+	Transaction domain.Transaction
+	Fingerprint string
+	Categories  []string
+	Created     time.Time
+}
+
+type recentTransactions struct {
+	mu    sync.Mutex
+	items map[string]recentTransaction
+}
+
+func newRecentTransactions() *recentTransactions {
+	return &recentTransactions{items: map[string]recentTransaction{}}
+}
+
+func (c *recentTransactions) Put(item recentTransaction) {
+	now := timeNow()
+	item.Created = now
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, v := range c.items {
+		if now.Sub(v.Created) > recentTransactionTTL {
+			delete(c.items, k)
+		}
+	}
+	c.items[item.Transaction.ID] = item
+}
+
+func (c *recentTransactions) Get(id string) (recentTransaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[id]
+	if ok && timeNow().Sub(item.Created) > recentTransactionTTL {
+		delete(c.items, id)
+		return item, false
+	}
+	return item, ok
+}
+
+func (c *recentTransactions) Update(update recentTransaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if item, ok := c.items[update.Transaction.ID]; ok {
+		item.Transaction = update.Transaction
+		item.Fingerprint = update.Fingerprint
+		c.items[update.Transaction.ID] = item
+	}
+}
+
+func (c *recentTransactions) Delete(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, id)
+}
+
+func newTransactionID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func (tg *TelegramBot) getRecentTransaction(userID int, id string) (domain.User, recentTransaction, error) {
+	user, err := tg.GetRepoUser(userID)
+	if err != nil {
+		return user, recentTransaction{}, err
+	}
+	item, ok := tg.recent.Get(id)
+	if !ok {
+		if item, err = tg.loadSavedTransaction(user, id); err != nil {
+			return user, item, err
+		}
+	}
+	if item.Transaction.UserID != user.ID {
+		return user, item, errors.New("transaction not found")
+	}
+	return user, item, nil
+}
+
+func (tg *TelegramBot) loadSavedTransaction(u domain.User, id string) (recentTransaction, error) {
+	if tg.ledger == nil {
+		return recentTransaction{}, errors.New("transaction is too old to change, please use the spreadsheet")
+	}
+	trans, err := tg.ledger.Get(context.Background(), u.ID, id)
+	if errors.Is(err, store.ErrTransactionNotFound) {
+		return recentTransaction{}, errors.New("transaction not found")
+	} else if err != nil {
+		return recentTransaction{}, err
+	}
+	item := recentTransaction{
+		Transaction: trans,
+		Fingerprint: transactionFingerprint(&trans, tg.transactionDated(u, trans)),
+	}
+	if item.Categories, err = tg.suggestUserCategories(u, &trans); err != nil {
+		log.Println("suggest categories: ", err)
+	}
+	tg.recent.Put(item)
+	return item, nil
+}
+
+func (tg *TelegramBot) transactionDated(u domain.User, trans domain.Transaction) bool {
+	if tg.patterns == nil {
+		return true
+	}
+	for _, v := range tg.patterns.Get(u) {
+		if v.Err == nil && v.Regexp.MatchString(trans.Raw) {
+			return messageHasDate(v.Regexp, trans.Raw)
+		}
+	}
+	return true
+}
+
+func (tg *TelegramBot) UpdateSavedTransaction(userID int, item recentTransaction, trans *domain.Transaction) (store.SinkResults, []string, error) {
+	var (
+		results store.SinkResults
+		alerts  []string
+	)
+	err := tg.wrapperRepoUserAndRepoTrx(userID, func(u domain.User, trx *store.MultiTransactionRepository) error {
+		ctx := context.Background()
+		if trans.UserID != u.ID || item.Transaction.ID != trans.ID {
+			return errors.New("transaction not found")
+		}
+		if err := tg.applyBaseCurrency(u, trans); err != nil {
+			log.Println("apply base currency: ", err)
+		}
+		results = trx.UpdateResults(ctx, trans)
+
+		pending := tg.updatePendingTransaction(ctx, u, *trans)
+		for i, v := range results {
+			if v.Err != nil && pending[v.Name] {
+				results[i].Queued = true
+			}
+		}
+
+		fingerprint := transactionFingerprint(trans, tg.transactionDated(u, *trans))
+		if tg.fingerprints != nil && fingerprint != item.Fingerprint {
+			if item.Fingerprint != "" {
+				if err := tg.fingerprints.Delete(ctx, u.ID, item.Fingerprint); err != nil {
+					log.Println("edit fingerprint: ", err)
+				}
+			}
+			if _, err := tg.fingerprints.Reserve(ctx, u.ID, fingerprint); err != nil {
+				log.Println("edit fingerprint: ", err)
+			}
+		}
+		tg.recent.Update(recentTransaction{Transaction: *trans, Fingerprint: fingerprint})
+
+		if tg.balances != nil {
+			if err := tg.balances.DeleteByTransaction(ctx, u.ID, trans.ID); err != nil {
+				log.Println("edit balance: ", err)
+			} else if warning, err := tg.trackBalance(u, trans); err != nil {
+				log.Println("edit balance: ", err)
+			} else if warning != "" {
+				alerts = append(alerts, warning)
+			}
+		}
+		budgetAlerts, err := tg.checkBudgets(u, trans)
+		if err != nil {
+			log.Println("edit budgets: ", err)
+		}
+		alerts = append(alerts, budgetAlerts...)
+		return nil
+	})
+	return results, alerts, err
+}
+
+func (tg *TelegramBot) updatePendingTransaction(ctx context.Context, u domain.User, trans domain.Transaction) map[string]bool {
+	updated := map[string]bool{}
+	if tg.outbox == nil {
+		return updated
+	}
+	pending, err := tg.outbox.ListByUser(ctx, u.ID)
+	if err != nil {
+		log.Println("edit outbox: ", err)
+	}
+	for _, v := range pending {
+		if v.Transaction.ID != trans.ID {
+			continue
+		}
+		v := v
+		v.Transaction = trans
+		if err := tg.outbox.Update(ctx, &v); err != nil {
+			log.Println("edit outbox: ", err)
+			continue
+		}
+		updated[v.Sink] = true
+	}
+	return updated
+}
+
+func (tg *TelegramBot) UndoSavedTransaction(userID int, item recentTransaction) (store.SinkResults, error) {
+	var results store.SinkResults
+	err := tg.wrapperRepoUserAndRepoTrx(userID, func(u domain.User, trx *store.MultiTransactionRepository) error {
+		ctx := context.Background()
+		trans := item.Transaction
+		if trans.UserID != u.ID {
+			return errors.New("transaction not found")
+		}
+		results = trx.DeleteResults(ctx, &trans)
+		tg.recent.Delete(trans.ID)
+
+		if tg.fingerprints != nil && item.Fingerprint != "" {
+			if err := tg.fingerprints.Delete(ctx, u.ID, item.Fingerprint); err != nil {
+				log.Println("undo fingerprint: ", err)
+			}
+		}
+		if tg.balances != nil {
+			if err := tg.balances.DeleteByTransaction(ctx, u.ID, trans.ID); err != nil {
+				log.Println("undo balance: ", err)
+			}
+		}
+		if tg.outbox != nil {
+			pending, err := tg.outbox.ListByUser(ctx, u.ID)
+			if err != nil {
+				log.Println("undo outbox: ", err)
+			}
+			for _, v := range pending {
+				if v.Transaction.ID != trans.ID {
+					continue
+				}
+				v := v
+				if err := tg.outbox.Delete(ctx, &v); err != nil {
+					log.Println("undo outbox: ", err)
+				}
+			}
+		}
+		return nil
+	})
+	return results, err
+}
+
+func (tg *TelegramBot) newSavedSelector(trans *domain.Transaction, categories []string) *telebot.ReplyMarkup {
+	selector := &telebot.ReplyMarkup{}
+	var rows []telebot.Row
+	if tg.categories != nil && trans.Party != "" {
+		rows = append(rows, tg.categoryRows(selector, trans.ID, categories)...)
+	}
+	rows = append(rows, selector.Row(
+		selector.Data("Edit", btnTransactionEdit.Unique, trans.ID),
+		selector.Data("Undo", btnTransactionUndo.Unique, trans.ID),
+	))
+	selector.Inline(rows...)
+	return selector
+}
+
+func formatTransaction(trans domain.Transaction) string {
+	txt := fmt.Sprintf("%s %s %s (%s)", trans.Party, trans.Amount.String(), trans.Currency, trans.Date.Format("02/01/2006"))
+	if trans.Category != "" {
+		txt += ", " + trans.Category
+	}
+	return txt
+}
+
+func (tg *TelegramBot) transactionUndoCallback(c *telebot.Callback) {
+	_ = tg.bot.Respond(c)
+	m := &telebot.Message{Sender: c.Sender}
+	_ = tg.wrapperErr(m, func() error {
+		_, item, err := tg.getRecentTransaction(c.Sender.ID, c.Data)
+		if err != nil {
+			return err
+		}
+		results, err := tg.UndoSavedTransaction(c.Sender.ID, item)
+		if err != nil {
+			return err
+		}
+		if c.Message != nil {
+			_, _ = tg.bot.EditReplyMarkup(c.Message, nil)
+		}
+		return tg.Send(c.Sender, "Transaction removed: "+formatTransaction(item.Transaction)+formatSinkResults(results))
+	})
+}
+
+func (tg *TelegramBot) transactionEditCallback(c *telebot.Callback) {
+	_ = tg.bot.Respond(c)
+	m := &telebot.Message{Sender: c.Sender}
+	_ = tg.wrapperErr(m, func() error {
+		user, item, err := tg.getRecentTransaction(c.Sender.ID, c.Data)
+		if err != nil {
+			return err
+		}
+		trans := item.Transaction
+
+		prompt := func(to *telebot.User, field string, value string) error {
+			return tg.Send(to, fmt.Sprintf("%s: %s\nPlease send new value or %q to keep it:", field, value, editKeep))
+		}
+		edit := func(sess *Session, msg *telebot.Message, fn func(trans *domain.Transaction, text string) error) error {
+			edited, _ := sess.Value(editTransactionKey).(domain.Transaction)
+			if text := strings.TrimSpace(msg.Text); text != editKeep {
+				if err := fn(&edited, text); err != nil {
+					return err
+				}
+			}
+			sess.AddValue(editTransactionKey, edited)
+			return nil
+		}
+
+		tg.wrapperSession(m, "Edit", func(cancel context.CancelFunc) Step {
+			return NewNextStep(func(ctx context.Context, sess *Session) error {
+				return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+					return tg.wrapperErr(msg, func() error {
+						sess.AddValue(editTransactionKey, trans)
+						err := edit(sess, msg, func(trans *domain.Transaction, text string) error {
+							amount, err := parseAmount(text, user.NumberFormat)
+							trans.Amount = amount
+							return err
+						})
+						if err != nil {
+							cancel()
+							return err
+						}
+						return prompt(msg.Sender, "Party", trans.Party)
+					})
+				})
+			}, NewNextStep(func(ctx context.Context, sess *Session) error {
+				return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+					return tg.wrapperErr(msg, func() error {
+						_ = edit(sess, msg, func(trans *domain.Transaction, text string) error {
+							trans.Party = text
+							return nil
+						})
+						return prompt(msg.Sender, "Date", trans.Date.Format("02/01/2006 15:04"))
+					})
+				})
+			}, NewNextStep(func(ctx context.Context, sess *Session) error {
+				return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+					return tg.wrapperErr(msg, func() error {
+						err := edit(sess, msg, func(trans *domain.Transaction, text string) error {
+							date, err := parseDate(text, nil, userLocation(user))
+							trans.Date = date
+							return err
+						})
+						if err != nil {
+							cancel()
+							return err
+						}
+						return prompt(msg.Sender, "Category", trans.Category)
+					})
+				})
+			}, NewStep(func(ctx context.Context, sess *Session) error {
+				defer cancel()
+				return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+					return tg.wrapperErr(msg, func() error {
+						_ = edit(sess, msg, func(trans *domain.Transaction, text string) error {
+							trans.Category = text
+							return nil
+						})
+						edited, _ := sess.Value(editTransactionKey).(domain.Transaction)
+						results, alerts, err := tg.UpdateSavedTransaction(msg.Sender.ID, item, &edited)
+						if err != nil {
+							return err
+						}
+						if err := tg.Send(msg.Sender, "Transaction updated: "+formatTransaction(edited)+formatSinkResults(results)); err != nil {
+							return err
+						}
+						for _, v := range alerts {
+							_ = tg.Send(msg.Sender, v)
+						}
+						return nil
+					})
+				})
+			}))))
+		})
+
+		return prompt(c.Sender, "Amount", trans.Amount.String())
+	})
+}
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/store"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func Test_recentTransactions(t *testing.T) {
+	defer func() { timeNow = time.Now }()
+	now := time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	recent := newRecentTransactions()
+	recent.Put(recentTransaction{Transaction: domain.Transaction{ID: "1", Party: "uber"}, Fingerprint: "hash"})
+
+	item, ok := recent.Get("1")
+	assert.True(t, ok)
+	assert.Equal(t, "uber", item.Transaction.Party)
+
+	recent.Update(recentTransaction{Transaction: domain.Transaction{ID: "1", Party: "UBER EATS"}, Fingerprint: "hash2"})
+	recent.Update(recentTransaction{Transaction: domain.Transaction{ID: "2", Party: "cafe"}})
+	item, _ = recent.Get("1")
+	assert.Equal(t, "UBER EATS", item.Transaction.Party)
+	assert.Equal(t, "hash2", item.Fingerprint)
+	_, ok = recent.Get("2")
+	assert.False(t, ok)
+
+	now = now.Add(recentTransactionTTL + time.Second)
+	_, ok = recent.Get("1")
+	assert.False(t, ok)
+
+	recent.Put(recentTransaction{Transaction: domain.Transaction{ID: "3"}})
+	recent.Delete("3")
+	_, ok = recent.Get("3")
+	assert.False(t, ok)
+}
+
+func Test_newTransactionID(t *testing.T) {
+	id := newTransactionID()
+	assert.Len(t, id, 16)
+	assert.NotEqual(t, id, newTransactionID())
+}
+
+func Test_getRecentTransaction_ledger(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open("file:bot_edit_ledger?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	require.NoError(t, err)
+	users := store.NewGormUserRepository(db)
+	require.NoError(t, users.Migration(ctx))
+	ledger := store.NewGormTransactionRepository(db)
+	require.NoError(t, ledger.Migration(ctx))
+
+	require.NoError(t, users.Store(ctx, &domain.User{BotUserID: 42}))
+	require.NoError(t, users.Store(ctx, &domain.User{BotUserID: 43}))
+	user, err := users.GetByBotUserID(ctx, 42)
+	require.NoError(t, err)
+	trans := domain.Transaction{ID: "t1", UserID: user.ID, Party: "CAFE", Amount: decimal.RequireFromString("-5"), Date: time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC)}
+	require.NoError(t, ledger.Store(ctx, &trans))
+
+	tg := &TelegramBot{userRepo: users, ledger: ledger, recent: newRecentTransactions()}
+	_, item, err := tg.getRecentTransaction(42, "t1")
+	if assert.NoError(t, err, "falls back to the ledger after a restart") {
+		assert.Equal(t, "CAFE", item.Transaction.Party)
+		assert.NotEmpty(t, item.Fingerprint)
+	}
+	_, ok := tg.recent.Get("t1")
+	assert.True(t, ok)
+
+	_, _, err = tg.getRecentTransaction(43, "t1")
+	assert.Error(t, err, "other user")
+	_, _, err = tg.getRecentTransaction(42, "unknown")
+	assert.Error(t, err)
+}
+
+func Test_UpdateSavedTransaction(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open("file:bot_edit_update?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	require.NoError(t, err)
+	users := store.NewGormUserRepository(db)
+	require.NoError(t, users.Migration(ctx))
+	ledger := store.NewGormTransactionRepository(db)
+	require.NoError(t, ledger.Migration(ctx))
+	outbox := store.NewGormOutboxRepository(db)
+	require.NoError(t, outbox.Migration(ctx))
+	fingerprints := store.NewGormFingerprintRepository(db)
+	require.NoError(t, fingerprints.Migration(ctx))
+	balances := store.NewGormBalanceRepository(db)
+	require.NoError(t, balances.Migration(ctx))
+
+	require.NoError(t, users.Store(ctx, &domain.User{BotUserID: 42}))
+	user, err := users.GetByBotUserID(ctx, 42)
+	require.NoError(t, err)
+
+	trans := domain.Transaction{ID: "t1", UserID: user.ID, Account: "5098", Party: "CAFE", Amount: decimal.RequireFromString("-5"), Currency: "AED",
+		Date: time.Date(2020, 11, 1, 10, 0, 0, 0, time.UTC), Total: decimal.RequireFromString("95")}
+	require.NoError(t, ledger.Store(ctx, &trans))
+	pending := domain.OutboxItem{UserID: user.ID, Sink: SinkGoogle, Transaction: trans, Attempts: 1, NextAttempt: time.Now().Add(time.Hour)}
+	require.NoError(t, outbox.Store(ctx, &pending))
+	item := recentTransaction{Transaction: trans, Fingerprint: transactionFingerprint(&trans, true)}
+	require.NoError(t, fingerprints.Store(ctx, user.ID, item.Fingerprint))
+	require.NoError(t, balances.Store(ctx, &domain.Balance{UserID: user.ID, Account: "5098", Currency: "AED", Amount: trans.Amount, Total: trans.Total, Date: trans.Date, TransactionID: "t1"}))
+
+	tg := &TelegramBot{userRepo: users, ledger: ledger, outbox: outbox, fingerprints: fingerprints, balances: balances,
+		recent: newRecentTransactions(), sinks: []string{SinkLedger, SinkGoogle}}
+	tg.recent.Put(item)
+
+	edited := trans
+	edited.Amount = decimal.RequireFromString("-7")
+	edited.Total = decimal.RequireFromString("93")
+	results, _, err := tg.UpdateSavedTransaction(42, item, &edited)
+	require.NoError(t, err)
+	for _, v := range results {
+		if v.Name == SinkGoogle {
+			assert.True(t, v.Queued, "pending outbox item carries the edit")
+		}
+	}
+
+	got, err := outbox.Get(ctx, pending.ID)
+	if assert.NoError(t, err) {
+		assert.True(t, edited.Amount.Equal(got.Transaction.Amount))
+	}
+
+	fingerprint := transactionFingerprint(&edited, true)
+	exists, err := fingerprints.Exists(ctx, user.ID, item.Fingerprint)
+	assert.NoError(t, err)
+	assert.False(t, exists, "old fingerprint released")
+	exists, err = fingerprints.Exists(ctx, user.ID, fingerprint)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	cached, _ := tg.recent.Get("t1")
+	assert.Equal(t, fingerprint, cached.Fingerprint)
+
+	balance, err := balances.Last(ctx, user.ID, "5098")
+	if assert.NoError(t, err) {
+		assert.True(t, edited.Total.Equal(balance.Total))
+	}
+}
@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/export"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var (
+	btnExportFormat = telebot.Btn{Unique: "exportFormat"}
+	btnExportPeriod = telebot.Btn{Unique: "exportPeriod"}
+)
+
+func exportTransactions(format string, items []domain.Transaction) []domain.Transaction {
+	if format != export.OFX && format != export.QIF {
+		return items
+	}
+	result := make([]domain.Transaction, 0, len(items))
+	for _, v := range items {
+		v.Amount = signedAmount(v)
+		result = append(result, v)
+	}
+	return result
+}
+
+func (tg *TelegramBot) buildRepoUserExport(userID int, format string, text string) (*telebot.Document, int, error) {
+	user, err := tg.GetRepoUser(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	period, err := parseReportPeriod(text, timeNow().In(userLocation(user)))
+	if err != nil {
+		return nil, 0, err
+	}
+	repo, err := tg.userQueryRepo(user)
+	if err != nil {
+		return nil, 0, err
+	}
+	items, err := repo.List(context.Background(), domain.TransactionFilter{UserID: user.ID, From: period.From, To: period.To})
+	if err != nil || len(items) == 0 {
+		return nil, 0, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := export.Write(buf, format, exportTransactions(format, items)); err != nil {
+		return nil, 0, err
+	}
+	return &telebot.Document{
+		File:     telebot.FromReader(buf),
+		FileName: export.FileName(format, period.From, period.To.Add(-time.Nanosecond)),
+		MIME:     export.MIME(format),
+		Caption:  fmt.Sprintf("%d transactions, %s", len(items), period),
+	}, len(items), nil
+}
+
+func (tg *TelegramBot) sendExport(to *telebot.User, format string, text string) error {
+	doc, count, err := tg.buildRepoUserExport(to.ID, format, text)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return tg.Send(to, "No transactions for this period.")
+	}
+	return tg.Send(to, doc)
+}
+
+func (tg *TelegramBot) exportHandler(_ telegramBotCommand, m *telebot.Message) {
+	selector := &telebot.ReplyMarkup{}
+	var buttons []telebot.Btn
+	for _, v := range export.Formats {
+		buttons = append(buttons, selector.Data(strings.ToUpper(v), btnExportFormat.Unique, v))
+	}
+	selector.Inline(selector.Row(buttons...))
+	_ = tg.Send(m.Sender, "Please choose file format:", selector)
+}
+
+func (tg *TelegramBot) exportFormatCallback(c *telebot.Callback) {
+	_ = tg.bot.Respond(c)
+	selector := &telebot.ReplyMarkup{}
+	selector.Inline(selector.Row(
+		selector.Data("This week", btnExportPeriod.Unique, c.Data, reportWeek),
+		selector.Data("This month", btnExportPeriod.Unique, c.Data, reportMonth),
+		selector.Data("Custom range", btnExportPeriod.Unique, c.Data, reportCustom),
+	))
+	_ = tg.Send(c.Sender, "Please choose period:", selector)
+}
+
+func (tg *TelegramBot) exportPeriodCallback(c *telebot.Callback) {
+	_ = tg.bot.Respond(c)
+	m := &telebot.Message{Sender: c.Sender}
+	parts := strings.SplitN(c.Data, "|", 2)
+	if len(parts) != 2 {
+		return
+	}
+	format, period := parts[0], parts[1]
+	if period != reportCustom {
+		_ = tg.wrapperErr(m, func() error {
+			return tg.sendExport(c.Sender, format, period)
+		})
+		return
+	}
+	_ = tg.Send(c.Sender, "Please send range as dd/mm/yyyy-dd/mm/yyyy or yyyy-mm-dd..yyyy-mm-dd:")
+	tg.wrapperSession(m, exportCommand.Name, func(cancel context.CancelFunc) Step {
+		return NewStep(func(ctx context.Context, sess *Session) error {
+			defer cancel()
+			return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+				return tg.wrapperErr(msg, func() error {
+					return tg.sendExport(msg.Sender, format, msg.Text)
+				})
+			})
+		})
+	})
+}
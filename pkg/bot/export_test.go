@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/export"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_exportTransactions(t *testing.T) {
+	items := []domain.Transaction{
+		{Amount: decimal.RequireFromString("-20"), Direction: "debit"},
+		{Amount: decimal.RequireFromString("100"), Direction: "credit"},
+		{Amount: decimal.RequireFromString("-5"), Direction: "credit"},
+	}
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{format: export.CSV, want: []string{"-20", "100", "-5"}},
+		{format: export.XLSX, want: []string{"-20", "100", "-5"}},
+		{format: export.OFX, want: []string{"-20", "100", "-5"}},
+		{format: export.QIF, want: []string{"-20", "100", "-5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var got []string
+			for _, v := range exportTransactions(tt.format, items) {
+				got = append(got, v.Amount.String())
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, "-20", items[0].Amount.String(), "source items untouched")
+		})
+	}
+}
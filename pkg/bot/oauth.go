@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	oauthCallbackPath = "/oauth/callback"
+	oauthStateTTL     = 15 * time.Minute
+)
+
+var (
+	btnGoogleManual = telebot.Btn{Unique: "googleManual"}
+)
+
+type oauthState struct {
+	BotUserID int
+	Created   time.Time
+}
+
+type oauthStates struct {
+	mu    sync.Mutex
+	items map[string]oauthState
+}
+
+func newOAuthStates() *oauthStates {
+	return &oauthStates{items: map[string]oauthState{}}
+}
+
+func (s *oauthStates) New(botUserID int) string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	state := hex.EncodeToString(buf)
+	now := timeNow()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range s.items {
+		if now.Sub(v.Created) > oauthStateTTL || v.BotUserID == botUserID {
+			delete(s.items, k)
+		}
+	}
+	s.items[state] = oauthState{BotUserID: botUserID, Created: now}
+	return state
+}
+
+func (s *oauthStates) Take(state string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[state]
+	delete(s.items, state)
+	if !ok || timeNow().Sub(item.Created) > oauthStateTTL {
+		return 0, false
+	}
+	return item.BotUserID, true
+}
+
+func WithOAuthCallback(addr string, publicURL string) Option {
+	return func(tg *TelegramBot) {
+		tg.oauthAddr = addr
+		tg.oauthRedirectURL = strings.TrimSuffix(publicURL, "/") + oauthCallbackPath
+	}
+}
+
+func (tg *TelegramBot) runOAuthServer(ctx context.Context) {
+	srv := &http.Server{Addr: tg.oauthAddr, Handler: tg.oauthHandler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Println("oauth callback server: ", err)
+	}
+}
+
+func (tg *TelegramBot) oauthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(oauthCallbackPath, tg.oauthCallbackHandler)
+	return mux
+}
+
+func (tg *TelegramBot) oauthCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userID, ok := tg.oauthStates.Take(query.Get("state"))
+	if !ok {
+		writeOAuthPage(w, http.StatusBadRequest, fmt.Sprintf("The link has expired, please send /%s to the bot again.", addGoogleTokenCommand.Command))
+		return
+	}
+	if reason := query.Get("error"); reason != "" {
+		tg.notifyUser(userID, fmt.Sprintf("Google token: 🚫 access was not granted (%s)", reason))
+		writeOAuthPage(w, http.StatusBadRequest, "Access was not granted, you can close this page.")
+		return
+	}
+
+	tok, err := tg.trxClient.WithRedirectURL(tg.oauthRedirectURL).GetToken(r.Context(), query.Get("code"))
+	if err == nil {
+		err = tg.SaveRepoUserGoogleToken(userID, tok)
+	}
+	if err != nil {
+		log.Println("oauth callback: ", err)
+		tg.notifyUser(userID, fmt.Sprintf("Google token: 🚫 %v", err))
+		writeOAuthPage(w, http.StatusInternalServerError, "Could not connect Google account, please try again from the bot.")
+		return
+	}
+	tg.notifyUser(userID, "Google token: ✔")
+	writeOAuthPage(w, http.StatusOK, "Google account connected, you can return to Telegram.")
+}
+
+func writeOAuthPage(w http.ResponseWriter, status int, text string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<!DOCTYPE html><html><head><meta charset=\"utf-8\"><title>Bank bot</title></head><body><p>%s</p></body></html>", html.EscapeString(text))
+}
+
+func (tg *TelegramBot) sendGoogleRegistration(m *telebot.Message) {
+	state := tg.oauthStates.New(m.Sender.ID)
+	selector := &telebot.ReplyMarkup{}
+	selector.Inline(
+		selector.Row(selector.URL("Connect Google account", tg.trxClient.WithRedirectURL(tg.oauthRedirectURL).NewStateRegistration(state))),
+		selector.Row(selector.Data("Enter code manually", btnGoogleManual.Unique)),
+	)
+	_ = tg.Send(m.Sender, "Please open the link and allow access, the token will be saved automatically.", selector)
+}
+
+func (tg *TelegramBot) googleManualCallback(c *telebot.Callback) {
+	_ = tg.bot.Respond(c)
+	tg.manualGoogleToken(&telebot.Message{Sender: c.Sender})
+}
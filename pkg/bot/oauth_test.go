@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func Test_oauthStates(t *testing.T) {
+	defer func(fn func() time.Time) { timeNow = fn }(timeNow)
+	now := time.Date(2020, 11, 1, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	states := newOAuthStates()
+	first := states.New(42)
+	second := states.New(42)
+	assert.NotEqual(t, first, second)
+
+	_, ok := states.Take(first)
+	assert.False(t, ok, "new state must replace previous one of the user")
+
+	userID, ok := states.Take(second)
+	assert.True(t, ok)
+	assert.Equal(t, 42, userID)
+
+	_, ok = states.Take(second)
+	assert.False(t, ok, "state must be single use")
+
+	expired := states.New(7)
+	now = now.Add(oauthStateTTL + time.Second)
+	_, ok = states.Take(expired)
+	assert.False(t, ok)
+}
+
+func Test_oauthCallbackHandler(t *testing.T) {
+	var redirectURI string
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		redirectURI = r.PostForm.Get("redirect_uri")
+		if r.PostForm.Get("code") != "good" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"a","refresh_token":"r","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer tokenSrv.Close()
+
+	db, err := gorm.Open(sqlite.Open("file:bot_oauth?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	require.NoError(t, err)
+	users := store.NewGormUserRepository(db)
+	require.NoError(t, users.Migration(context.Background()))
+	require.NoError(t, users.Store(context.Background(), &domain.User{BotUserID: 42}))
+
+	tg := &TelegramBot{
+		userRepo: users,
+		trxClient: store.NewGoogleClient(&oauth2.Config{
+			ClientID: "id",
+			Endpoint: oauth2.Endpoint{TokenURL: tokenSrv.URL, AuthStyle: oauth2.AuthStyleInParams},
+		}),
+		oauthStates: newOAuthStates(),
+	}
+	WithOAuthCallback(":0", "https://bot.example.com/")(tg)
+	assert.Equal(t, "https://bot.example.com/oauth/callback", tg.oauthRedirectURL)
+
+	call := func(query url.Values) int {
+		rec := httptest.NewRecorder()
+		tg.oauthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, oauthCallbackPath+"?"+query.Encode(), nil))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, call(url.Values{"state": {"unknown"}, "code": {"good"}}))
+	assert.Equal(t, http.StatusBadRequest, call(url.Values{"state": {tg.oauthStates.New(42)}, "error": {"access_denied"}}))
+	assert.Equal(t, http.StatusInternalServerError, call(url.Values{"state": {tg.oauthStates.New(42)}, "code": {"bad"}}))
+
+	user, err := tg.GetRepoUser(42)
+	require.NoError(t, err)
+	assert.Nil(t, user.TokSheet)
+
+	state := tg.oauthStates.New(42)
+	assert.Equal(t, http.StatusOK, call(url.Values{"state": {state}, "code": {"good"}}))
+	assert.Equal(t, tg.oauthRedirectURL, redirectURI)
+	assert.Equal(t, http.StatusBadRequest, call(url.Values{"state": {state}, "code": {"good"}}))
+
+	user, err = tg.GetRepoUser(42)
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(user.TokSheet), `"access_token":"a"`)
+	}
+}
@@ -0,0 +1,222 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/store"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	outboxInterval    = time.Minute
+	outboxBatchSize   = 50
+	outboxBaseBackoff = time.Minute
+	outboxMaxBackoff  = 6 * time.Hour
+	outboxClaimTime   = 10 * time.Minute
+	outboxMaxAttempts = 20
+)
+
+var (
+	btnOutboxRetry = telebot.Btn{Unique: "outboxRetry"}
+	btnOutboxDrop  = telebot.Btn{Unique: "outboxDrop"}
+
+	errOutboxBusy = errors.New("pending transaction is already being retried")
+)
+
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return backoff
+}
+
+func (tg *TelegramBot) enqueueFailedSinks(u domain.User, trans *domain.Transaction, results store.SinkResults) {
+	if tg.outbox == nil {
+		return
+	}
+	for i, v := range results {
+		if v.Err == nil || v.Unavailable {
+			continue
+		}
+		item := &domain.OutboxItem{
+			UserID:      u.ID,
+			Sink:        v.Name,
+			Transaction: *trans,
+			Attempts:    1,
+			NextAttempt: time.Now().Add(outboxBackoff(1)),
+			LastError:   v.Err.Error(),
+		}
+		if err := tg.outbox.Store(context.Background(), item); err != nil {
+			log.Println("outbox store: ", err)
+			continue
+		}
+		results[i].Queued = true
+	}
+}
+
+func (tg *TelegramBot) RetryOutboxItem(ctx context.Context, item *domain.OutboxItem) error {
+	claimed, err := tg.outbox.Claim(ctx, item, time.Now().Add(outboxClaimTime))
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return errOutboxBusy
+	}
+	user, err := tg.userRepo.Get(ctx, item.UserID)
+	if err != nil {
+		return err
+	}
+	repo, err := tg.newSinkRepo(item.Sink, user)
+	if err == nil {
+		err = repo.Store(ctx, &item.Transaction)
+	}
+	if err == nil {
+		return tg.outbox.Delete(ctx, item)
+	}
+	item.NextAttempt = time.Now().Add(outboxBackoff(item.Attempts))
+	item.LastError = err.Error()
+	item.Dead = item.Attempts >= outboxMaxAttempts
+	if uErr := tg.outbox.Update(ctx, item); uErr != nil {
+		return uErr
+	}
+	return err
+}
+
+func (tg *TelegramBot) runOutbox(ctx context.Context) {
+	ticker := time.NewTicker(outboxInterval)
+	defer ticker.Stop()
+	for {
+		tg.retryDueOutbox(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (tg *TelegramBot) retryDueOutbox(ctx context.Context) {
+	items, err := tg.outbox.ListDue(ctx, time.Now(), outboxBatchSize)
+	if err != nil {
+		log.Println("outbox list due: ", err)
+		return
+	}
+	for _, v := range items {
+		item := v
+		err := tg.RetryOutboxItem(ctx, &item)
+		if err != nil {
+			log.Println("outbox retry: ", err)
+			if !item.Dead {
+				continue
+			}
+		}
+		user, uErr := tg.userRepo.Get(ctx, item.UserID)
+		if uErr != nil {
+			continue
+		}
+		txt := fmt.Sprintf("Pending transaction saved to %s: %s", item.Sink, formatOutboxTransaction(item.Transaction))
+		if err != nil {
+			txt = fmt.Sprintf("Pending transaction to %s failed %d times and will not be retried automatically: %s\nSee /pending to retry or drop it.",
+				item.Sink, item.Attempts, formatOutboxTransaction(item.Transaction))
+		}
+		tg.notifyUser(user.BotUserID, txt)
+	}
+}
+
+func formatOutboxTransaction(trx domain.Transaction) string {
+	return fmt.Sprintf("%s %s %s (%s)", trx.Party, trx.Amount.String(), trx.Currency, trx.Date.Format("02/01/2006"))
+}
+
+func (tg *TelegramBot) pendingHandler(_ telegramBotCommand, m *telebot.Message) {
+	_ = tg.wrapperErr(m, func() error {
+		if tg.outbox == nil {
+			return errors.New("outbox not configured")
+		}
+		user, err := tg.GetRepoUser(m.Sender.ID)
+		if err != nil {
+			return err
+		}
+		items, err := tg.outbox.ListByUser(context.Background(), user.ID)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return tg.Send(m.Sender, "Nothing pending. ✔")
+		}
+
+		selector := &telebot.ReplyMarkup{}
+		var rows []telebot.Row
+		var lines []string
+		for _, v := range items {
+			id := strconv.FormatUint(uint64(v.ID), 10)
+			next := v.NextAttempt.Format("02/01/2006 15:04")
+			if v.Dead {
+				next = "gave up, retry manually"
+			}
+			lines = append(lines, fmt.Sprintf("#%s %s: %s\n  attempts: %d, next: %s\n  error: %s",
+				id, v.Sink, formatOutboxTransaction(v.Transaction), v.Attempts, next, v.LastError))
+			rows = append(rows, selector.Row(
+				selector.Data("Retry #"+id, btnOutboxRetry.Unique, id),
+				selector.Data("Drop #"+id, btnOutboxDrop.Unique, id),
+			))
+		}
+		selector.Inline(rows...)
+
+		return tg.Send(m.Sender, "Pending:\n\n"+strings.Join(lines, "\n\n"), selector)
+	})
+}
+
+func (tg *TelegramBot) wrapperOutboxCallback(c *telebot.Callback, fn func(item *domain.OutboxItem) error) {
+	_ = tg.bot.Respond(c)
+	m := &telebot.Message{Sender: c.Sender}
+	_ = tg.wrapperErr(m, func() error {
+		if tg.outbox == nil {
+			return errors.New("outbox not configured")
+		}
+		id, err := strconv.ParseUint(c.Data, 10, 64)
+		if err != nil {
+			return err
+		}
+		user, err := tg.GetRepoUser(c.Sender.ID)
+		if err != nil {
+			return err
+		}
+		item, err := tg.outbox.Get(context.Background(), uint(id))
+		if err != nil {
+			return err
+		}
+		if item.UserID != user.ID {
+			return errors.New("pending item not found")
+		}
+		return fn(&item)
+	})
+}
+
+func (tg *TelegramBot) outboxRetryCallback(c *telebot.Callback) {
+	tg.wrapperOutboxCallback(c, func(item *domain.OutboxItem) error {
+		if err := tg.RetryOutboxItem(context.Background(), item); err != nil {
+			return err
+		}
+		return tg.Send(c.Sender, fmt.Sprintf("Pending #%d saved to %s. ✔", item.ID, item.Sink))
+	})
+}
+
+func (tg *TelegramBot) outboxDropCallback(c *telebot.Callback) {
+	tg.wrapperOutboxCallback(c, func(item *domain.OutboxItem) error {
+		if err := tg.outbox.Delete(context.Background(), item); err != nil {
+			return err
+		}
+		return tg.Send(c.Sender, fmt.Sprintf("Pending #%d dropped.", item.ID))
+	})
+}
@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/store"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func Test_outboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: time.Minute},
+		{attempts: 1, want: time.Minute},
+		{attempts: 2, want: 2 * time.Minute},
+		{attempts: 4, want: 8 * time.Minute},
+		{attempts: 9, want: 256 * time.Minute},
+		{attempts: 10, want: 6 * time.Hour},
+		{attempts: 100, want: 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, outboxBackoff(tt.attempts), "attempts %d", tt.attempts)
+	}
+}
+
+func Test_RetryOutboxItem_claimed(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open("file:bot_outbox?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	require.NoError(t, err)
+	users := store.NewGormUserRepository(db)
+	require.NoError(t, users.Migration(ctx))
+	ledger := store.NewGormTransactionRepository(db)
+	require.NoError(t, ledger.Migration(ctx))
+	outbox := store.NewGormOutboxRepository(db)
+	require.NoError(t, outbox.Migration(ctx))
+
+	require.NoError(t, users.Store(ctx, &domain.User{BotUserID: 42}))
+	user, err := users.GetByBotUserID(ctx, 42)
+	require.NoError(t, err)
+
+	item := domain.OutboxItem{
+		UserID:      user.ID,
+		Sink:        SinkLedger,
+		Transaction: domain.Transaction{ID: "t1", UserID: user.ID, Party: "CAFE", Amount: decimal.RequireFromString("-5"), Date: time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC)},
+		Attempts:    1,
+		NextAttempt: time.Now().Add(-time.Minute),
+	}
+	require.NoError(t, outbox.Store(ctx, &item))
+
+	tg := &TelegramBot{userRepo: users, ledger: ledger, outbox: outbox}
+	worker, button := item, item
+	assert.NoError(t, tg.RetryOutboxItem(ctx, &worker))
+	assert.Equal(t, errOutboxBusy, tg.RetryOutboxItem(ctx, &button))
+
+	items, err := ledger.List(ctx, domain.TransactionFilter{UserID: user.ID})
+	if assert.NoError(t, err) {
+		assert.Len(t, items, 1, "transaction stored once")
+	}
+}
+
+func Test_RetryOutboxItem_dead(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open("file:bot_outbox_dead?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	require.NoError(t, err)
+	users := store.NewGormUserRepository(db)
+	require.NoError(t, users.Migration(ctx))
+	outbox := store.NewGormOutboxRepository(db)
+	require.NoError(t, outbox.Migration(ctx))
+
+	require.NoError(t, users.Store(ctx, &domain.User{BotUserID: 42}))
+	user, err := users.GetByBotUserID(ctx, 42)
+	require.NoError(t, err)
+
+	item := domain.OutboxItem{
+		UserID:      user.ID,
+		Sink:        SinkLedger,
+		Transaction: domain.Transaction{ID: "t1", UserID: user.ID},
+		Attempts:    outboxMaxAttempts - 1,
+		NextAttempt: time.Now().Add(-time.Minute),
+	}
+	require.NoError(t, outbox.Store(ctx, &item))
+
+	tg := &TelegramBot{userRepo: users, outbox: outbox}
+	tg.retryDueOutbox(ctx)
+
+	got, err := outbox.Get(ctx, item.ID)
+	if assert.NoError(t, err) {
+		assert.True(t, got.Dead)
+		assert.Equal(t, outboxMaxAttempts, got.Attempts)
+	}
+	items, err := outbox.ListDue(ctx, time.Now().Add(outboxMaxBackoff), outboxBatchSize)
+	if assert.NoError(t, err) {
+		assert.Len(t, items, 0)
+	}
+}
@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ftomza/go-bank-bot/domain"
+)
+
+type compiledPattern struct {
+	Pattern domain.TrxPattern
+	Regexp  *regexp.Regexp
+	Err     error
+}
+
+type patternCache struct {
+	mu    sync.RWMutex
+	items map[uint]cachedPatterns
+}
+
+type cachedPatterns struct {
+	key      string
+	compiled []compiledPattern
+}
+
+func newPatternCache() *patternCache {
+	return &patternCache{items: map[uint]cachedPatterns{}}
+}
+
+func (c *patternCache) Get(u domain.User) []compiledPattern {
+	key := patternsKey(u.TrxPatterns)
+
+	c.mu.RLock()
+	item, ok := c.items[u.ID]
+	c.mu.RUnlock()
+	if ok && item.key == key {
+		return item.compiled
+	}
+
+	item = cachedPatterns{key: key, compiled: compilePatterns(u.TrxPatterns)}
+
+	c.mu.Lock()
+	c.items[u.ID] = item
+	c.mu.Unlock()
+
+	return item.compiled
+}
+
+func (c *patternCache) Invalidate(userID uint) {
+	c.mu.Lock()
+	delete(c.items, userID)
+	c.mu.Unlock()
+}
+
+func patternsKey(patterns []domain.TrxPattern) string {
+	key, _ := json.Marshal(patterns)
+	return string(key)
+}
+
+func compilePatterns(patterns []domain.TrxPattern) []compiledPattern {
+	var compiled []compiledPattern
+	for _, v := range patterns {
+		re, err := regexp.Compile(v.Pattern)
+		compiled = append(compiled, compiledPattern{Pattern: v, Regexp: re, Err: err})
+	}
+	return compiled
+}
+
+func validatePatterns(patterns []string) (warnings []string, err error) {
+	var errs []string
+	for i, v := range patterns {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		re, err := regexp.Compile(v)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("line %d: %v", i+1, err))
+			continue
+		}
+		if re.SubexpIndex("amount") == -1 {
+			warnings = append(warnings, fmt.Sprintf("line %d: named group \"amount\" is missing", i+1))
+		}
+	}
+	if len(errs) > 0 {
+		return warnings, errors.New("invalid patterns:\n" + strings.Join(errs, "\n"))
+	}
+	return warnings, nil
+}
+
+func parsePatternSettings(pattern *domain.TrxPattern, settings string) error {
+	for i, line := range strings.Split(settings, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("line %d: expected key=value", i+1)
+		}
+		key, value := strings.ToLower(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1])
+		switch key {
+		case "name":
+			pattern.Name = value
+		case "currency":
+			pattern.Currency = value
+		case "direction":
+			pattern.Direction = value
+		case "account":
+			pattern.Account = value
+		case "income":
+			pattern.Income = splitList(value)
+		case "expense":
+			pattern.Expense = splitList(value)
+		case "number":
+			format, err := parseNumberFormat(value)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", i+1, err)
+			}
+			pattern.NumberFormat = format
+		case "date":
+			pattern.DateLayouts = splitList(value)
+		default:
+			return fmt.Errorf("line %d: unknown setting %q", i+1, key)
+		}
+	}
+	return nil
+}
+
+func formatPatternSettings(pattern domain.TrxPattern) string {
+	number := ""
+	if pattern.NumberFormat != nil {
+		number = formatNumberFormat(pattern.NumberFormat)
+	}
+	return fmt.Sprintf("name=%s\ncurrency=%s\ndirection=%s\naccount=%s\nincome=%s\nexpense=%s\nnumber=%s\ndate=%s",
+		pattern.Name,
+		pattern.Currency,
+		pattern.Direction,
+		pattern.Account,
+		strings.Join(pattern.Income, ", "),
+		strings.Join(pattern.Expense, ", "),
+		number,
+		strings.Join(pattern.DateLayouts, ", "),
+	)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			items = append(items, v)
+		}
+	}
+	return items
+}
@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validatePatterns(t *testing.T) {
+	tests := []struct {
+		name         string
+		patterns     []string
+		wantWarnings []string
+		wantErr      string
+	}{
+		{
+			name:     "ok",
+			patterns: []string{`^(?P<amount>[0-9.]+) (?P<currency>[A-Z]{3})$`, ""},
+		},
+		{
+			name:         "warning amount",
+			patterns:     []string{`^(?P<amount>[0-9.]+)$`, `^(?P<total>[0-9.]+)$`},
+			wantWarnings: []string{`line 2: named group "amount" is missing`},
+		},
+		{
+			name:     "invalid",
+			patterns: []string{`^(?P<amount>[0-9.]+)$`, `^(?P<amount>[0-9.]+$`},
+			wantErr:  "invalid patterns:\nline 2: error parsing regexp: missing closing ): `^(?P<amount>[0-9.]+$`",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := validatePatterns(tt.patterns)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantWarnings, warnings)
+		})
+	}
+}
+
+func Test_patternCache(t *testing.T) {
+	cache := newPatternCache()
+	user := domain.User{ID: 1, TrxPatterns: []domain.TrxPattern{{Pattern: `(?P<amount>\d+)`}, {Pattern: `(`}}}
+
+	compiled := cache.Get(user)
+	if assert.Len(t, compiled, 2) {
+		assert.NoError(t, compiled[0].Err)
+		assert.Error(t, compiled[1].Err)
+	}
+	assert.Same(t, compiled[0].Regexp, cache.Get(user)[0].Regexp)
+
+	user.TrxPatterns = user.TrxPatterns[:1]
+	assert.Len(t, cache.Get(user), 1)
+
+	cache.Invalidate(user.ID)
+	assert.NotSame(t, compiled[0].Regexp, cache.Get(user)[0].Regexp)
+}
+
+func Test_parsePatternSettings(t *testing.T) {
+	pattern := domain.TrxPattern{Pattern: `(?P<amount>\d+)`, Name: "Pattern 1", Currency: "USD"}
+	err := parsePatternSettings(&pattern, "name = Card\ncurrency=\ndirection=debit\naccount=Main\ndate=02.01.2006, 02.01")
+	if assert.NoError(t, err) {
+		assert.Equal(t, domain.TrxPattern{
+			Pattern:     `(?P<amount>\d+)`,
+			Name:        "Card",
+			DateLayouts: []string{"02.01.2006", "02.01"},
+			Direction:   "debit",
+			Account:     "Main",
+		}, pattern)
+	}
+
+	assert.EqualError(t, parsePatternSettings(&pattern, "name=Card\ncolor=red"), `line 2: unknown setting "color"`)
+	assert.EqualError(t, parsePatternSettings(&pattern, "name"), `line 1: expected key=value`)
+}
@@ -0,0 +1,140 @@
+package bot
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+//go:embed presets.json
+var presetsData []byte
+
+var presetCatalog = mustLoadPresets(presetsData)
+
+type PresetSample struct {
+	Message  string            `json:"message"`
+	Expected map[string]string `json:"expected"`
+}
+
+type Preset struct {
+	ID          string         `json:"id"`
+	Version     int            `json:"version"`
+	Name        string         `json:"name"`
+	Pattern     string         `json:"pattern"`
+	DateLayouts []string       `json:"date_layouts"`
+	Currency    string         `json:"currency"`
+	Direction   string         `json:"direction"`
+	Account     string         `json:"account"`
+	Income      []string       `json:"income"`
+	Expense     []string       `json:"expense"`
+	Samples     []PresetSample `json:"samples"`
+
+	NumberFormat *domain.NumberFormat `json:"number_format"`
+}
+
+func (p Preset) TrxPattern() domain.TrxPattern {
+	return domain.TrxPattern{
+		Pattern:     p.Pattern,
+		Name:        p.Name,
+		DateLayouts: p.DateLayouts,
+		Currency:    p.Currency,
+		Direction:   p.Direction,
+		Account:     p.Account,
+		Income:      p.Income,
+		Expense:     p.Expense,
+
+		NumberFormat: p.NumberFormat,
+
+		Preset:  p.ID,
+		Version: p.Version,
+	}
+}
+
+func loadPresets(data []byte) ([]Preset, error) {
+	var presets []Preset
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, err
+	}
+	ids := map[string]bool{}
+	for _, v := range presets {
+		if v.ID == "" || ids[v.ID] {
+			return nil, fmt.Errorf("presets: bad or duplicate id %q", v.ID)
+		}
+		ids[v.ID] = true
+	}
+	return presets, nil
+}
+
+func mustLoadPresets(data []byte) []Preset {
+	presets, err := loadPresets(data)
+	if err != nil {
+		panic(err)
+	}
+	return presets
+}
+
+func findPreset(id string) (Preset, bool) {
+	for _, v := range presetCatalog {
+		if v.ID == id {
+			return v, true
+		}
+	}
+	return Preset{}, false
+}
+
+var btnPreset = telebot.Btn{Unique: "preset"}
+
+func (tg *TelegramBot) newPresetsSelector(u domain.User) *telebot.ReplyMarkup {
+	enabled := map[string]int{}
+	for _, v := range u.TrxPatterns {
+		if v.Preset != "" {
+			enabled[v.Preset] = v.Version
+		}
+	}
+
+	selector := &telebot.ReplyMarkup{}
+	var rows []telebot.Row
+	for _, v := range presetCatalog {
+		text := v.Name
+		if version, ok := enabled[v.ID]; ok && version < v.Version {
+			text = fmt.Sprintf("⬆ %s (v%d)", v.Name, v.Version)
+		} else if ok {
+			text = "✔ " + v.Name
+		}
+		rows = append(rows, selector.Row(selector.Data(text, btnPreset.Unique, v.ID)))
+	}
+	selector.Inline(rows...)
+	return selector
+}
+
+func (tg *TelegramBot) presetsHandler(_ telegramBotCommand, m *telebot.Message) {
+	_ = tg.wrapperErr(m, func() error {
+		user, err := tg.GetOrCreateRepoUser(m.Sender.ID)
+		if err != nil {
+			return err
+		}
+		return tg.Send(m.Sender, "Patterns library, tap to enable or disable:", tg.newPresetsSelector(user))
+	})
+}
+
+func (tg *TelegramBot) presetCallback(c *telebot.Callback) {
+	_ = tg.bot.Respond(c)
+	_ = tg.wrapperErr(&telebot.Message{Sender: c.Sender}, func() error {
+		enabled, err := tg.ToggleRepoUserPreset(c.Sender.ID, c.Data)
+		if err != nil {
+			return err
+		}
+		user, err := tg.GetRepoUser(c.Sender.ID)
+		if err != nil {
+			return err
+		}
+		if c.Message != nil {
+			_, _ = tg.bot.EditReplyMarkup(c.Message, tg.newPresetsSelector(user))
+		}
+		preset, _ := findPreset(c.Data)
+		return tg.Send(c.Sender, fmt.Sprintf("%s: %s", preset.Name, IfThenElse(enabled, "✔", "🚫")))
+	})
+}
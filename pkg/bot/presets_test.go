@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_presetCatalog(t *testing.T) {
+	if !assert.NotEmpty(t, presetCatalog) {
+		return
+	}
+	for _, preset := range presetCatalog {
+		t.Run(preset.ID, func(t *testing.T) {
+			assert.NotEmpty(t, preset.Name)
+			assert.NotZero(t, preset.Version)
+			warnings, err := validatePatterns([]string{preset.Pattern})
+			assert.NoError(t, err)
+			assert.Empty(t, warnings)
+
+			if !assert.NotEmpty(t, preset.Samples, "preset without samples") {
+				return
+			}
+			for _, sample := range preset.Samples {
+				trans, err := prepareTransactionOfPattern(preset.TrxPattern(), sample.Message)
+				if !assert.NoError(t, err, sample.Message) || !assert.NotNil(t, trans, sample.Message) {
+					continue
+				}
+				got := map[string]string{
+					"account":   trans.Account,
+					"party":     trans.Party,
+					"direction": trans.Direction,
+					"amount":    trans.Amount.String(),
+					"currency":  trans.Currency,
+					"date":      trans.Date.Format("02/01/2006"),
+					"total":     trans.Total.String(),
+				}
+				for key, want := range sample.Expected {
+					assert.Equal(t, want, got[key], "%s: %s", key, sample.Message)
+				}
+			}
+		})
+	}
+}
+
+func Test_loadPresets(t *testing.T) {
+	_, err := loadPresets([]byte(`[{"id":"a"},{"id":"a"}]`))
+	assert.EqualError(t, err, `presets: bad or duplicate id "a"`)
+}
@@ -0,0 +1,349 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	reportWeek   = "week"
+	reportMonth  = "month"
+	reportCustom = "custom"
+
+	reportTopParties = 10
+)
+
+var (
+	btnReport = telebot.Btn{Unique: "report"}
+)
+
+type reportPeriod struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+func (p reportPeriod) Previous() reportPeriod {
+	switch p.Name {
+	case reportWeek:
+		return reportPeriod{Name: p.Name, From: p.From.AddDate(0, 0, -7), To: p.From}
+	case reportMonth:
+		return reportPeriod{Name: p.Name, From: p.From.AddDate(0, -1, 0), To: p.From}
+	}
+	return reportPeriod{Name: p.Name, From: p.From.Add(-p.To.Sub(p.From)), To: p.From}
+}
+
+func (p reportPeriod) String() string {
+	return p.From.Format("02/01/2006") + " - " + p.To.Add(-time.Nanosecond).Format("02/01/2006")
+}
+
+func parseReportPeriod(text string, now time.Time) (reportPeriod, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch text {
+	case "", reportMonth:
+		from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return reportPeriod{Name: reportMonth, From: from, To: from.AddDate(0, 1, 0)}, nil
+	case reportWeek:
+		from := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return reportPeriod{Name: reportWeek, From: from, To: from.AddDate(0, 0, 7)}, nil
+	}
+	var parts []string
+	switch {
+	case strings.Contains(text, ".."):
+		parts = strings.Split(text, "..")
+	case strings.Contains(text, " - "):
+		parts = strings.Split(text, " - ")
+	default:
+		parts = strings.Split(text, "-")
+	}
+	if len(parts) != 2 {
+		return reportPeriod{}, fmt.Errorf("wrong period %q, expected week, month or dd/mm/yyyy-dd/mm/yyyy", text)
+	}
+	from, err := parseDate(parts[0], nil, now.Location())
+	if err != nil {
+		return reportPeriod{}, err
+	}
+	to, err := parseDate(parts[1], nil, now.Location())
+	if err != nil {
+		return reportPeriod{}, err
+	}
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, to.Location()).AddDate(0, 0, 1)
+	if !from.Before(to) {
+		return reportPeriod{}, fmt.Errorf("wrong period %q", text)
+	}
+	return reportPeriod{Name: reportCustom, From: from, To: to}, nil
+}
+
+type currencyTotals map[string]decimal.Decimal
+
+func (t currencyTotals) Add(currency string, amount decimal.Decimal) {
+	t[currency] = t[currency].Add(amount)
+}
+
+func (t currencyTotals) Currencies() []string {
+	var result []string
+	for k := range t {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+type reportGroup struct {
+	Name   string
+	Totals currencyTotals
+	Count  int
+}
+
+type report struct {
+	Period           reportPeriod
+	Totals           currencyTotals
+	Spent            currencyTotals
+	Received         currencyTotals
+	PreviousSpent    currencyTotals
+	PreviousReceived currencyTotals
+	Count            int
+	ByCategory       []reportGroup
+	ByParty          []reportGroup
+	ByDirection      []reportGroup
+}
+
+func groupTransactions(items []domain.Transaction, key func(trans domain.Transaction) string) []reportGroup {
+	groups := map[string]*reportGroup{}
+	var order []string
+	for _, v := range items {
+		name := key(v)
+		group, ok := groups[name]
+		if !ok {
+			group = &reportGroup{Name: name, Totals: currencyTotals{}}
+			groups[name] = group
+			order = append(order, name)
+		}
+		group.Totals.Add(v.Currency, signedAmount(v))
+		group.Count++
+	}
+	var result []reportGroup
+	for _, name := range order {
+		result = append(result, *groups[name])
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return reportGroupWeight(result[i]).GreaterThan(reportGroupWeight(result[j]))
+	})
+	return result
+}
+
+func reportGroupWeight(group reportGroup) decimal.Decimal {
+	weight := decimal.Zero
+	for _, v := range group.Totals {
+		weight = weight.Add(v.Abs())
+	}
+	return weight
+}
+
+func buildReport(period reportPeriod, items []domain.Transaction, previous []domain.Transaction) report {
+	result := report{
+		Period:           period,
+		Totals:           currencyTotals{},
+		Spent:            currencyTotals{},
+		Received:         currencyTotals{},
+		PreviousSpent:    currencyTotals{},
+		PreviousReceived: currencyTotals{},
+		Count:            len(items),
+	}
+	for _, v := range items {
+		result.Totals.Add(v.Currency, signedAmount(v))
+		if isExpense(v) {
+			result.Spent.Add(v.Currency, v.Amount.Abs())
+		} else {
+			result.Received.Add(v.Currency, v.Amount.Abs())
+		}
+	}
+	for _, v := range previous {
+		if isExpense(v) {
+			result.PreviousSpent.Add(v.Currency, v.Amount.Abs())
+		} else {
+			result.PreviousReceived.Add(v.Currency, v.Amount.Abs())
+		}
+	}
+	result.ByCategory = groupTransactions(items, func(trans domain.Transaction) string {
+		return IfThenElse(trans.Category == "", "Uncategorized", trans.Category).(string)
+	})
+	result.ByParty = groupTransactions(items, func(trans domain.Transaction) string {
+		return IfThenElse(trans.Party == "", "Unknown", trans.Party).(string)
+	})
+	result.ByDirection = groupTransactions(items, func(trans domain.Transaction) string {
+		return IfThenElse(trans.Direction == "", "Unknown", trans.Direction).(string)
+	})
+	return result
+}
+
+func formatCurrencyTotals(totals currencyTotals) string {
+	var items []string
+	for _, v := range totals.Currencies() {
+		items = append(items, strings.TrimSpace(totals[v].StringFixed(2)+" "+v))
+	}
+	return strings.Join(items, ", ")
+}
+
+func formatTotalsChange(current, previous decimal.Decimal) string {
+	if previous.IsZero() {
+		return "new"
+	}
+	change := current.Abs().Sub(previous.Abs()).Div(previous.Abs()).Mul(decimal.NewFromInt(100))
+	sign := ""
+	if change.IsPositive() {
+		sign = "+"
+	}
+	return sign + change.StringFixed(1) + "%"
+}
+
+func formatReportGroups(title string, groups []reportGroup, limit int) string {
+	txt := fmt.Sprintf("\n*%s*:\n", EscapeMarkdown2(title))
+	for i, v := range groups {
+		if limit > 0 && i >= limit {
+			txt += EscapeMarkdown2(fmt.Sprintf("- ... %d more", len(groups)-limit)) + "\n"
+			break
+		}
+		txt += EscapeMarkdown2(fmt.Sprintf("- %s (%d): %s", v.Name, v.Count, formatCurrencyTotals(v.Totals))) + "\n"
+	}
+	return txt
+}
+
+func formatReport(r report) string {
+	txt := fmt.Sprintf("__Report for %s__\n", EscapeMarkdown2(r.Period.String()))
+	if r.Count == 0 {
+		return txt + "\nNo transactions\\."
+	}
+	txt += fmt.Sprintf("\n*Total* \\(%d\\):\n", r.Count)
+	var currencies []string
+	seen := map[string]bool{}
+	for _, totals := range []currencyTotals{r.Totals, r.PreviousSpent, r.PreviousReceived} {
+		for _, v := range totals.Currencies() {
+			if !seen[v] {
+				seen[v] = true
+				currencies = append(currencies, v)
+			}
+		}
+	}
+	for _, v := range currencies {
+		line := fmt.Sprintf("- %s: spent %s, previous %s (%s)", valueOrDefault(v, "Unknown"),
+			r.Spent[v].StringFixed(2), r.PreviousSpent[v].StringFixed(2), formatTotalsChange(r.Spent[v], r.PreviousSpent[v]))
+		if !r.Received[v].IsZero() || !r.PreviousReceived[v].IsZero() {
+			line += fmt.Sprintf("; received %s, previous %s (%s); net %s", r.Received[v].StringFixed(2),
+				r.PreviousReceived[v].StringFixed(2), formatTotalsChange(r.Received[v], r.PreviousReceived[v]), r.Totals[v].StringFixed(2))
+		}
+		txt += EscapeMarkdown2(line) + "\n"
+	}
+	txt += formatReportGroups("By category", r.ByCategory, 0)
+	txt += formatReportGroups("By direction", r.ByDirection, 0)
+	txt += formatReportGroups("Top parties", r.ByParty, reportTopParties)
+	return txt
+}
+
+func (tg *TelegramBot) buildRepoUserReport(userID int, text string) (report, error) {
+	user, err := tg.GetRepoUser(userID)
+	if err != nil {
+		return report{}, err
+	}
+	period, err := parseReportPeriod(text, timeNow().In(userLocation(user)))
+	if err != nil {
+		return report{}, err
+	}
+	return tg.buildUserReport(user, period)
+}
+
+func (tg *TelegramBot) buildUserReport(user domain.User, period reportPeriod) (report, error) {
+	repo, err := tg.userQueryRepo(user)
+	if err != nil {
+		return report{}, err
+	}
+	ctx := context.Background()
+	items, err := repo.List(ctx, domain.TransactionFilter{UserID: user.ID, From: period.From, To: period.To})
+	if err != nil {
+		return report{}, err
+	}
+	previous := period.Previous()
+	previousItems, err := repo.List(ctx, domain.TransactionFilter{UserID: user.ID, From: previous.From, To: previous.To})
+	if err != nil {
+		return report{}, err
+	}
+	return buildReport(period, toBaseCurrency(items, user.BaseCurrency), toBaseCurrency(previousItems, user.BaseCurrency)), nil
+}
+
+func (tg *TelegramBot) userQueryRepo(u domain.User) (domain.TransactionRepository, error) {
+	var errs []string
+	for _, name := range []string{SinkLedger, SinkGoogle} {
+		found := false
+		for _, v := range tg.sinks {
+			found = found || v == name
+		}
+		if !found {
+			continue
+		}
+		repo, err := tg.newSinkRepo(name, u)
+		if err == nil {
+			return repo, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no transaction storage configured")
+	}
+	return nil, errors.New(strings.Join(errs, "; "))
+}
+
+func (tg *TelegramBot) sendReport(to *telebot.User, text string) error {
+	r, err := tg.buildRepoUserReport(to.ID, text)
+	if err != nil {
+		return err
+	}
+	return tg.Send(to, formatReport(r), telebot.ModeMarkdownV2)
+}
+
+func (tg *TelegramBot) reportHandler(_ telegramBotCommand, m *telebot.Message) {
+	if m.Payload != "" {
+		_ = tg.wrapperErr(m, func() error {
+			return tg.sendReport(m.Sender, m.Payload)
+		})
+		return
+	}
+	selector := &telebot.ReplyMarkup{}
+	selector.Inline(selector.Row(
+		selector.Data("This week", btnReport.Unique, reportWeek),
+		selector.Data("This month", btnReport.Unique, reportMonth),
+		selector.Data("Custom range", btnReport.Unique, reportCustom),
+	))
+	_ = tg.Send(m.Sender, "Please choose period:", selector)
+}
+
+func (tg *TelegramBot) reportCallback(c *telebot.Callback) {
+	_ = tg.bot.Respond(c)
+	m := &telebot.Message{Sender: c.Sender}
+	if c.Data != reportCustom {
+		_ = tg.wrapperErr(m, func() error {
+			return tg.sendReport(c.Sender, c.Data)
+		})
+		return
+	}
+	_ = tg.Send(c.Sender, "Please send range as dd/mm/yyyy-dd/mm/yyyy or yyyy-mm-dd..yyyy-mm-dd:")
+	tg.wrapperSession(m, reportCommand.Name, func(cancel context.CancelFunc) Step {
+		return NewStep(func(ctx context.Context, sess *Session) error {
+			defer cancel()
+			return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+				return tg.wrapperErr(msg, func() error {
+					return tg.sendReport(msg.Sender, msg.Text)
+				})
+			})
+		})
+	})
+}
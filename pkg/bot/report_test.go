@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseReportPeriod(t *testing.T) {
+	now := time.Date(2020, 11, 12, 15, 30, 0, 0, time.UTC)
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		text         string
+		want         reportPeriod
+		wantPrevious reportPeriod
+		wantErr      bool
+	}{
+		{
+			text:         "",
+			want:         reportPeriod{Name: reportMonth, From: date(2020, 11, 1), To: date(2020, 12, 1)},
+			wantPrevious: reportPeriod{Name: reportMonth, From: date(2020, 10, 1), To: date(2020, 11, 1)},
+		},
+		{
+			text:         "Week",
+			want:         reportPeriod{Name: reportWeek, From: date(2020, 11, 9), To: date(2020, 11, 16)},
+			wantPrevious: reportPeriod{Name: reportWeek, From: date(2020, 11, 2), To: date(2020, 11, 9)},
+		},
+		{
+			text:         "01/11/2020-10/11/2020",
+			want:         reportPeriod{Name: reportCustom, From: date(2020, 11, 1), To: date(2020, 11, 11)},
+			wantPrevious: reportPeriod{Name: reportCustom, From: date(2020, 10, 22), To: date(2020, 11, 1)},
+		},
+		{
+			text:         "2020-10-01..2020-10-31",
+			want:         reportPeriod{Name: reportCustom, From: date(2020, 10, 1), To: date(2020, 11, 1)},
+			wantPrevious: reportPeriod{Name: reportCustom, From: date(2020, 8, 31), To: date(2020, 10, 1)},
+		},
+		{text: "10/11/2020-01/11/2020", wantErr: true},
+		{text: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := parseReportPeriod(tt.text, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.wantPrevious, got.Previous())
+			}
+		})
+	}
+}
+
+func Test_formatReport(t *testing.T) {
+	period := reportPeriod{
+		Name: reportMonth,
+		From: time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC),
+	}
+	items := []domain.Transaction{
+		{Party: "UBER", Direction: "debit", Amount: decimal.RequireFromString("-20.5"), Currency: "AED", Category: "Transport"},
+		{Party: "UBER", Direction: "debit", Amount: decimal.RequireFromString("-10"), Currency: "AED", Category: "Transport"},
+		{Party: "CAFE", Direction: "debit", Amount: decimal.RequireFromString("-5"), Currency: "USD"},
+	}
+	previous := []domain.Transaction{
+		{Party: "UBER", Amount: decimal.RequireFromString("-61"), Currency: "AED"},
+	}
+
+	r := buildReport(period, items, previous)
+	assert.Equal(t, 3, r.Count)
+	assert.Len(t, r.ByCategory, 2)
+	assert.Equal(t, "Transport", r.ByCategory[0].Name)
+	assert.Equal(t, 2, r.ByCategory[0].Count)
+
+	assert.Equal(t, `__Report for 01/11/2020 \- 30/11/2020__
+
+*Total* \(3\):
+\- AED: spent 30\.50, previous 61\.00 \(\-50\.0%\)
+\- USD: spent 5\.00, previous 0\.00 \(new\)
+
+*By category*:
+\- Transport \(2\): \-30\.50 AED
+\- Uncategorized \(1\): \-5\.00 USD
+
+*By direction*:
+\- debit \(3\): \-30\.50 AED, \-5\.00 USD
+
+*Top parties*:
+\- UBER \(2\): \-30\.50 AED
+\- CAFE \(1\): \-5\.00 USD
+`, formatReport(r))
+
+	assert.Equal(t, "__Report for 01/11/2020 \\- 30/11/2020__\n\nNo transactions\\.", formatReport(buildReport(period, nil, previous)))
+}
+
+func Test_buildReport_incomeAndExpense(t *testing.T) {
+	period := reportPeriod{
+		Name: reportMonth,
+		From: time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC),
+	}
+	items := []domain.Transaction{
+		{Party: "ACME", Direction: "credit", Amount: decimal.RequireFromString("1000"), Currency: "AED", Category: "Salary"},
+		{Party: "CAFE", Direction: "debit", Amount: decimal.RequireFromString("-5"), Currency: "AED", Category: "Food"},
+	}
+	previous := []domain.Transaction{
+		{Party: "ACME", Direction: "credit", Amount: decimal.RequireFromString("800"), Currency: "AED"},
+		{Party: "CAFE", Direction: "debit", Amount: decimal.RequireFromString("-10"), Currency: "AED"},
+	}
+
+	r := buildReport(period, items, previous)
+	assert.Equal(t, "995.00 AED", formatCurrencyTotals(r.Totals))
+	assert.Equal(t, "5.00 AED", formatCurrencyTotals(r.Spent))
+	assert.Equal(t, "1000.00 AED", formatCurrencyTotals(r.Received))
+	assert.Contains(t, formatReport(r),
+		`\- AED: spent 5\.00, previous 10\.00 \(\-50\.0%\); received 1000\.00, previous 800\.00 \(\+25\.0%\); net 995\.00`)
+	assert.Contains(t, formatReport(r), `\- Salary \(1\): 1000\.00 AED`)
+	assert.Contains(t, formatReport(r), `\- Food \(1\): \-5\.00 AED`)
+}
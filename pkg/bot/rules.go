@@ -0,0 +1,338 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+var (
+	btnRuleAdd    = telebot.Btn{Unique: "ruleAdd"}
+	btnRuleDelete = telebot.Btn{Unique: "ruleDelete"}
+
+	ruleRegexps = newRegexpCache()
+)
+
+type regexpCache struct {
+	mu    sync.RWMutex
+	items map[string]cachedRegexp
+}
+
+type cachedRegexp struct {
+	re  *regexp.Regexp
+	err error
+}
+
+func newRegexpCache() *regexpCache {
+	return &regexpCache{items: map[string]cachedRegexp{}}
+}
+
+func (c *regexpCache) Compile(pattern string) (*regexp.Regexp, error) {
+	c.mu.RLock()
+	item, ok := c.items[pattern]
+	c.mu.RUnlock()
+	if ok {
+		return item.re, item.err
+	}
+
+	item.re, item.err = regexp.Compile(pattern)
+
+	c.mu.Lock()
+	c.items[pattern] = item
+	c.mu.Unlock()
+
+	return item.re, item.err
+}
+
+func applyRules(rules []domain.Rule, trans *domain.Transaction) {
+	for _, v := range rules {
+		if !ruleMatches(v, trans) {
+			continue
+		}
+		if trans.Category == "" {
+			trans.Category = v.Category
+		}
+		trans.Tags = mergeTags(trans.Tags, v.Tags)
+	}
+}
+
+func ruleMatches(rule domain.Rule, trans *domain.Transaction) bool {
+	if rule.Party != "" && !strings.Contains(strings.ToLower(trans.Party), strings.ToLower(rule.Party)) {
+		return false
+	}
+	if rule.PartyRegex != "" {
+		re, err := ruleRegexps.Compile(rule.PartyRegex)
+		if err != nil || !re.MatchString(trans.Party) {
+			return false
+		}
+	}
+	amount := trans.Amount.Abs()
+	if rule.MinAmount.Valid && amount.LessThan(rule.MinAmount.Decimal) {
+		return false
+	}
+	if rule.MaxAmount.Valid && amount.GreaterThan(rule.MaxAmount.Decimal) {
+		return false
+	}
+	if rule.Account != "" && !strings.EqualFold(rule.Account, trans.Account) {
+		return false
+	}
+	if rule.Direction != "" && !strings.EqualFold(rule.Direction, trans.Direction) {
+		return false
+	}
+	return true
+}
+
+func mergeTags(tags []string, add []string) []string {
+	for _, v := range add {
+		found := false
+		for _, t := range tags {
+			if strings.EqualFold(t, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			tags = append(tags, v)
+		}
+	}
+	return tags
+}
+
+func parseAmountRange(text string) (min, max decimal.NullDecimal, err error) {
+	text = strings.TrimSpace(text)
+	parse := func(v string) (decimal.NullDecimal, error) {
+		if v = strings.TrimSpace(v); v == "" {
+			return decimal.NullDecimal{}, nil
+		}
+		d, err := decimal.NewFromString(v)
+		if err != nil {
+			return decimal.NullDecimal{}, fmt.Errorf("can't convert %q to amount", v)
+		}
+		return decimal.NullDecimal{Decimal: d, Valid: true}, nil
+	}
+	switch {
+	case text == "":
+		return min, max, nil
+	case strings.HasPrefix(text, ">"):
+		min, err = parse(strings.TrimPrefix(strings.TrimPrefix(text, ">"), "="))
+		return min, max, err
+	case strings.HasPrefix(text, "<"):
+		max, err = parse(strings.TrimPrefix(strings.TrimPrefix(text, "<"), "="))
+		return min, max, err
+	}
+	parts := strings.SplitN(strings.Replace(text, "..", "-", 1), "-", 2)
+	if min, err = parse(parts[0]); err != nil {
+		return min, max, err
+	}
+	if len(parts) == 1 {
+		return min, min, nil
+	}
+	if max, err = parse(parts[1]); err != nil {
+		return min, max, err
+	}
+	if min.Valid && max.Valid && min.Decimal.GreaterThan(max.Decimal) {
+		return min, max, fmt.Errorf("wrong amount range %q", text)
+	}
+	return min, max, nil
+}
+
+func formatAmountRange(min, max decimal.NullDecimal) string {
+	switch {
+	case min.Valid && max.Valid:
+		return min.Decimal.String() + "-" + max.Decimal.String()
+	case min.Valid:
+		return ">" + min.Decimal.String()
+	case max.Valid:
+		return "<" + max.Decimal.String()
+	}
+	return ""
+}
+
+func parseRule(rule *domain.Rule, settings string) error {
+	for i, line := range strings.Split(settings, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("line %d: expected key=value", i+1)
+		}
+		key, value := strings.ToLower(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1])
+		switch key {
+		case "party":
+			rule.Party = value
+		case "regex":
+			if _, err := regexp.Compile(value); err != nil {
+				return fmt.Errorf("line %d: %w", i+1, err)
+			}
+			rule.PartyRegex = value
+		case "amount":
+			min, max, err := parseAmountRange(value)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", i+1, err)
+			}
+			rule.MinAmount, rule.MaxAmount = min, max
+		case "account":
+			rule.Account = value
+		case "direction":
+			rule.Direction = value
+		case "category":
+			rule.Category = value
+		case "tags":
+			rule.Tags = nil
+			for _, v := range strings.Split(value, ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					rule.Tags = append(rule.Tags, v)
+				}
+			}
+		default:
+			return fmt.Errorf("line %d: unknown setting %q", i+1, key)
+		}
+	}
+	if rule.Category == "" && len(rule.Tags) == 0 {
+		return errors.New("category or tags required")
+	}
+	return nil
+}
+
+func formatRule(rule domain.Rule) string {
+	var conditions []string
+	if rule.Party != "" {
+		conditions = append(conditions, fmt.Sprintf("party~%q", rule.Party))
+	}
+	if rule.PartyRegex != "" {
+		conditions = append(conditions, fmt.Sprintf("party=/%s/", rule.PartyRegex))
+	}
+	if amount := formatAmountRange(rule.MinAmount, rule.MaxAmount); amount != "" {
+		conditions = append(conditions, "amount "+amount)
+	}
+	if rule.Account != "" {
+		conditions = append(conditions, "account "+rule.Account)
+	}
+	if rule.Direction != "" {
+		conditions = append(conditions, "direction "+rule.Direction)
+	}
+	if len(conditions) == 0 {
+		conditions = append(conditions, "any")
+	}
+	result := strings.Join(conditions, ", ") + " → " + rule.Category
+	if len(rule.Tags) > 0 {
+		result += " [" + strings.Join(rule.Tags, ", ") + "]"
+	}
+	return result
+}
+
+func (tg *TelegramBot) applyUserRules(u domain.User, trans *domain.Transaction) error {
+	if tg.rules == nil {
+		return nil
+	}
+	rules, err := tg.rules.ListByUser(context.Background(), u.ID)
+	if err != nil {
+		return err
+	}
+	applyRules(rules, trans)
+	return nil
+}
+
+func (tg *TelegramBot) ListRepoUserRules(userID int) ([]domain.Rule, error) {
+	if tg.rules == nil {
+		return nil, errors.New("rules not configured")
+	}
+	user, err := tg.GetRepoUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return tg.rules.ListByUser(context.Background(), user.ID)
+}
+
+func (tg *TelegramBot) AddRepoUserRule(userID int, settings string) (domain.Rule, error) {
+	rule := domain.Rule{}
+	if tg.rules == nil {
+		return rule, errors.New("rules not configured")
+	}
+	if err := parseRule(&rule, settings); err != nil {
+		return rule, err
+	}
+	err := tg.wrapperRepoUser(userID, func(u domain.User) error {
+		rule.UserID = u.ID
+		return tg.rules.Store(context.Background(), &rule)
+	})
+	return rule, err
+}
+
+func (tg *TelegramBot) DeleteRepoUserRule(userID int, ruleID uint) error {
+	if tg.rules == nil {
+		return errors.New("rules not configured")
+	}
+	return tg.wrapperRepoUser(userID, func(u domain.User) error {
+		return tg.rules.Delete(context.Background(), &domain.Rule{ID: ruleID, UserID: u.ID})
+	})
+}
+
+func (tg *TelegramBot) rulesHandler(_ telegramBotCommand, m *telebot.Message) {
+	_ = tg.wrapperErr(m, func() error {
+		rules, err := tg.ListRepoUserRules(m.Sender.ID)
+		if err != nil {
+			return err
+		}
+
+		selector := &telebot.ReplyMarkup{}
+		rows := []telebot.Row{selector.Row(selector.Data("Add rule", btnRuleAdd.Unique))}
+		var lines []string
+		for _, v := range rules {
+			id := strconv.FormatUint(uint64(v.ID), 10)
+			lines = append(lines, fmt.Sprintf("#%s %s", id, formatRule(v)))
+			rows = append(rows, selector.Row(selector.Data("Delete #"+id, btnRuleDelete.Unique, id)))
+		}
+		selector.Inline(rows...)
+
+		if len(lines) == 0 {
+			return tg.Send(m.Sender, "Rules not set.", selector)
+		}
+		return tg.Send(m.Sender, "Rules, the first match sets the category:\n\n"+strings.Join(lines, "\n"), selector)
+	})
+}
+
+func (tg *TelegramBot) addRuleHandler(c telegramBotCommand, m *telebot.Message) {
+	_ = tg.Send(m.Sender, "Please send rule as key=value lines:\n\n"+
+		"party=uber\nregex=^UBER\\s\namount=10-50\naccount=1234\ndirection=debit\ncategory=Transport\ntags=taxi, work\n\n"+
+		"Conditions are optional, amount accepts 10-50, >10 or <50.")
+
+	tg.wrapperSession(m, c.Name, func(cancel context.CancelFunc) Step {
+		return NewStep(func(ctx context.Context, sess *Session) error {
+			defer cancel()
+			return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+				return tg.wrapperErr(msg, func() error {
+					rule, err := tg.AddRepoUserRule(msg.Sender.ID, strings.TrimSpace(msg.Text))
+					if err != nil {
+						return err
+					}
+					return tg.Send(msg.Sender, fmt.Sprintf("Rule #%d: ✔\n\n%s", rule.ID, formatRule(rule)))
+				})
+			})
+		})
+	})
+}
+
+func (tg *TelegramBot) ruleDeleteCallback(c *telebot.Callback) {
+	_ = tg.bot.Respond(c)
+	m := &telebot.Message{Sender: c.Sender}
+	_ = tg.wrapperErr(m, func() error {
+		id, err := strconv.ParseUint(c.Data, 10, 64)
+		if err != nil {
+			return err
+		}
+		if err := tg.DeleteRepoUserRule(c.Sender.ID, uint(id)); err != nil {
+			return err
+		}
+		return tg.Send(c.Sender, fmt.Sprintf("Rule #%d deleted.", id))
+	})
+}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_applyRules(t *testing.T) {
+	amount := func(v int64) decimal.NullDecimal {
+		return decimal.NullDecimal{Decimal: decimal.NewFromInt(v), Valid: true}
+	}
+	rules := []domain.Rule{
+		{Party: "uber", MaxAmount: amount(50), Category: "Transport", Tags: []string{"taxi"}},
+		{PartyRegex: `^UBER\s+EATS`, Category: "Food", Tags: []string{"delivery"}},
+		{Account: "1234", Direction: "debit", Tags: []string{"Card", "taxi"}},
+		{MinAmount: amount(1000), Category: "Big"},
+	}
+	tests := []struct {
+		name         string
+		trans        domain.Transaction
+		wantCategory string
+		wantTags     []string
+	}{
+		{
+			name:         "party and amount",
+			trans:        domain.Transaction{Party: "UBER TRIP", Amount: decimal.NewFromInt(-20)},
+			wantCategory: "Transport",
+			wantTags:     []string{"taxi"},
+		},
+		{
+			name:         "first match wins, tags merged",
+			trans:        domain.Transaction{Party: "UBER EATS", Amount: decimal.NewFromInt(30), Account: "1234", Direction: "Debit"},
+			wantCategory: "Transport",
+			wantTags:     []string{"taxi", "delivery", "Card"},
+		},
+		{
+			name:         "amount out of range",
+			trans:        domain.Transaction{Party: "UBER EATS", Amount: decimal.NewFromInt(70)},
+			wantCategory: "Food",
+			wantTags:     []string{"delivery"},
+		},
+		{
+			name:         "min amount",
+			trans:        domain.Transaction{Party: "Shop", Amount: decimal.NewFromInt(1000)},
+			wantCategory: "Big",
+		},
+		{
+			name:  "no match",
+			trans: domain.Transaction{Party: "Shop", Amount: decimal.NewFromInt(10), Account: "1234", Direction: "credit"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trans := tt.trans
+			applyRules(rules, &trans)
+			assert.Equal(t, tt.wantCategory, trans.Category)
+			assert.Equal(t, tt.wantTags, trans.Tags)
+		})
+	}
+}
+
+func Test_parseAmountRange(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{text: "", want: ""},
+		{text: "10-50", want: "10-50"},
+		{text: "10..50.5", want: "10-50.5"},
+		{text: ">100", want: ">100"},
+		{text: "<=20", want: "<20"},
+		{text: "25", want: "25-25"},
+		{text: "50-10", wantErr: true},
+		{text: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			min, max, err := parseAmountRange(tt.text)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, formatAmountRange(min, max))
+		})
+	}
+}
+
+func Test_parseRule(t *testing.T) {
+	rule := domain.Rule{}
+	err := parseRule(&rule, "party=uber\namount=<50\ncategory=Transport\ntags=taxi, work")
+	assert.NoError(t, err)
+	assert.Equal(t, `party~"uber", amount <50 → Transport [taxi, work]`, formatRule(rule))
+
+	assert.EqualError(t, parseRule(&domain.Rule{}, "party=uber"), "category or tags required")
+	assert.Error(t, parseRule(&domain.Rule{}, "regex=(\ncategory=x"))
+	assert.EqualError(t, parseRule(&domain.Rule{}, "foo=bar"), `line 1: unknown setting "foo"`)
+}
+
+func Test_regexpCache(t *testing.T) {
+	cache := newRegexpCache()
+	re, err := cache.Compile(`^UBER`)
+	if assert.NoError(t, err) {
+		again, _ := cache.Compile(`^UBER`)
+		assert.Same(t, re, again, "compiled once")
+	}
+	_, err = cache.Compile(`(`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/store"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	sheetTitle   = "Bank transactions"
+	sheetSingle  = "single"
+	sheetMonthly = "monthly"
+)
+
+var (
+	btnSheetCreate = telebot.Btn{Unique: "sheetCreate"}
+)
+
+func sheetURL(sheetID string) string {
+	return "https://docs.google.com/spreadsheets/d/" + sheetID
+}
+
+func (tg *TelegramBot) CreateRepoUserSheet(userID int, monthly bool) (domain.User, error) {
+	user, err := tg.GetOrCreateRepoUser(userID)
+	if err != nil {
+		return user, err
+	}
+	if userAuthMode(user) == store.GoogleAuthServiceAccount {
+		return user, errors.New("sheets created by the service account are not visible to you, please create a sheet and share it with the service account")
+	}
+	client, err := tg.googleHTTPClient(user)
+	if err != nil {
+		return user, err
+	}
+	spreadsheet, err := store.NewGoogleSpreadsheet(client)
+	if err != nil {
+		return user, err
+	}
+
+	now := timeNow().In(userLocation(user))
+	lists, list := store.GoogleDefaultLists(), store.GoogleDefaultLists()[0]
+	if monthly {
+		lists, list = store.GoogleMonthlyLists(now.Year()), store.GoogleMonthlyList(now)
+	}
+	sheetID, err := spreadsheet.Create(context.Background(), sheetTitle, lists)
+	if err != nil {
+		return user, err
+	}
+
+	err = tg.wrapperRepoUser(userID, func(u domain.User) error {
+		u.SheetID = sheetID
+		u.ListName = list
+		u.MonthlyList = monthly
+		u.Formatted = true
+		user = u
+		return tg.userRepo.UpdateFields(context.Background(), &u, "SheetID", "ListName", "MonthlyList", "Formatted")
+	})
+	return user, err
+}
+
+func (tg *TelegramBot) createSheetHandler(_ telegramBotCommand, m *telebot.Message) {
+	selector := &telebot.ReplyMarkup{}
+	selector.Inline(
+		selector.Row(
+			selector.Data("Single list", btnSheetCreate.Unique, sheetSingle),
+			selector.Data("Monthly tabs", btnSheetCreate.Unique, sheetMonthly),
+		),
+	)
+	_ = tg.Send(m.Sender, "A new spreadsheet will be created in your Google account.\nPlease choose layout:", selector)
+}
+
+func (tg *TelegramBot) createSheetCallback(c *telebot.Callback) {
+	_ = tg.bot.Respond(c)
+	m := &telebot.Message{Sender: c.Sender}
+	_ = tg.wrapperErr(m, func() error {
+		user, err := tg.CreateRepoUserSheet(c.Sender.ID, c.Data == sheetMonthly)
+		if err != nil {
+			return err
+		}
+		return tg.Send(c.Sender, fmt.Sprintf("Sheet created: ✔\n\n%s\nList: %s", sheetURL(user.SheetID), user.ListName))
+	})
+}
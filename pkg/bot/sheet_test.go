@@ -0,0 +1,34 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func Test_CreateRepoUserSheet_serviceAccount(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:bot_sheet?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	require.NoError(t, err)
+	users := store.NewGormUserRepository(db)
+	require.NoError(t, users.Migration(context.Background()))
+	require.NoError(t, users.Store(context.Background(), &domain.User{BotUserID: 42, AuthMode: store.GoogleAuthServiceAccount, SheetID: "sheet", MonthlyList: true, Formatted: true}))
+
+	tg := &TelegramBot{userRepo: users}
+	_, err = tg.CreateRepoUserSheet(42, true)
+	assert.Error(t, err)
+
+	require.NoError(t, tg.SaveRepoUserSheetList(42, "Manual"))
+	user, err := tg.GetRepoUser(42)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "sheet", user.SheetID)
+		assert.Equal(t, "Manual", user.ListName)
+		assert.False(t, user.MonthlyList)
+		assert.False(t, user.Formatted, "a manual list has no bot formats")
+	}
+}
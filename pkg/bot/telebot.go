@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
@@ -15,6 +18,13 @@ import (
 	"gopkg.in/tucnak/telebot.v2"
 )
 
+type sessionKey string
+
+const (
+	patternIndexKey    sessionKey = "patternIndex"
+	editTransactionKey sessionKey = "editTransaction"
+)
+
 var (
 	currentMessage = struct{}{}
 
@@ -55,13 +65,35 @@ func (c telegramBotCommand) CallMessageHandler(msg *telebot.Message) {
 }
 
 var (
-	startCommand          = telegramBotCommand{Name: "Start", Command: "start", Description: "Start bot"}
-	mainCommand           = telegramBotCommand{Name: "Main", Command: "main", Description: "Show main menu"}
-	addGoogleTokenCommand = telegramBotCommand{Name: "AddGoogleToken", Command: "addgoogletoken", Description: "Add google token"}
-	setSheetCommand       = telegramBotCommand{Name: "SetSheet", Command: "setsheet", Description: "Set Google sheet id for parse data"}
-	setSheetListCommand   = telegramBotCommand{Name: "SetSheetList", Command: "setsheetlist", Description: "Set Google sheet list for parse data"}
-	setPatternsCommand    = telegramBotCommand{Name: "SetPatterns", Command: "setpatterns", Description: "Set Patterns for parsing input message"}
-	cancelCommand         = telegramBotCommand{Name: "Cancel", Command: "cancel", Description: "Cancel current operation"}
+	startCommand           = telegramBotCommand{Name: "Start", Command: "start", Description: "Start bot"}
+	mainCommand            = telegramBotCommand{Name: "Main", Command: "main", Description: "Show main menu"}
+	addGoogleTokenCommand  = telegramBotCommand{Name: "AddGoogleToken", Command: "addgoogletoken", Description: "Add google token"}
+	authModeCommand        = telegramBotCommand{Name: "AuthMode", Command: "authmode", Description: "Choose Google authentication mode"}
+	setSheetCommand        = telegramBotCommand{Name: "SetSheet", Command: "setsheet", Description: "Set Google sheet id for parse data"}
+	setSheetListCommand    = telegramBotCommand{Name: "SetSheetList", Command: "setsheetlist", Description: "Set Google sheet list for parse data"}
+	createSheetCommand     = telegramBotCommand{Name: "CreateSheet", Command: "createsheet", Description: "Create and format a new Google sheet"}
+	setPatternsCommand     = telegramBotCommand{Name: "SetPatterns", Command: "setpatterns", Description: "Set Patterns for parsing input message"}
+	patternSettingsCommand = telegramBotCommand{Name: "PatternSettings", Command: "patternsettings", Description: "Set name and defaults of a pattern"}
+	presetsCommand         = telegramBotCommand{Name: "Presets", Command: "presets", Description: "Browse built-in patterns library"}
+	numberFormatCommand    = telegramBotCommand{Name: "NumberFormat", Command: "numberformat", Description: "Set default number format of amounts"}
+	timezoneCommand        = telegramBotCommand{Name: "Timezone", Command: "timezone", Description: "Set timezone of bank messages"}
+	baseCurrencyCommand    = telegramBotCommand{Name: "BaseCurrency", Command: "basecurrency", Description: "Set base currency for reports"}
+	testPatternCommand     = telegramBotCommand{Name: "TestPattern", Command: "testpattern", Description: "Show how a message would be parsed"}
+	duplicatesCommand      = telegramBotCommand{Name: "Duplicates", Command: "duplicates", Description: "Toggle saving of duplicate messages"}
+	pendingCommand         = telegramBotCommand{Name: "Pending", Command: "pending", Description: "Show transactions waiting for retry"}
+	rulesCommand           = telegramBotCommand{Name: "Rules", Command: "rules", Description: "Show categorization rules"}
+	addRuleCommand         = telegramBotCommand{Name: "AddRule", Command: "addrule", Description: "Add categorization rule"}
+	reportCommand          = telegramBotCommand{Name: "Report", Command: "report", Description: "Show spending summary"}
+	exportCommand          = telegramBotCommand{Name: "Export", Command: "export", Description: "Export transactions to a file"}
+	digestCommand          = telegramBotCommand{Name: "Digest", Command: "digest", Description: "Schedule spending digest"}
+	budgetCommand          = telegramBotCommand{Name: "Budget", Command: "budget", Description: "Manage monthly budgets"}
+	balanceCommand         = telegramBotCommand{Name: "Balance", Command: "balance", Description: "Show last known account balances"}
+	cancelCommand          = telegramBotCommand{Name: "Cancel", Command: "cancel", Description: "Cancel current operation"}
+)
+
+const (
+	SinkGoogle = "google"
+	SinkLedger = "ledger"
 )
 
 type sessionBot struct {
@@ -73,11 +105,88 @@ type TelegramBot struct {
 	bot       *telebot.Bot
 	userRepo  domain.UserRepository
 	trxClient *store.GoogleClient
+	ledger    domain.LedgerRepository
+	outbox    domain.OutboxRepository
+	sinks     []string
 	sessions  map[int]sessionBot
 
+	fingerprints        domain.FingerprintRepository
+	rules               domain.RuleRepository
+	categories          domain.CategoryRepository
+	budgets             domain.BudgetRepository
+	balances            domain.BalanceRepository
+	rates               domain.RatesProvider
+	serviceAccount      *store.GoogleServiceAccount
+	serviceAccountUsers map[int]bool
+	recent              *recentTransactions
+	patterns            *patternCache
+
+	oauthAddr        string
+	oauthRedirectURL string
+	oauthStates      *oauthStates
+
+	cancelWorkers context.CancelFunc
+	workers       sync.WaitGroup
+
 	startSelector *telebot.ReplyMarkup
 }
 
+type Option func(tg *TelegramBot)
+
+func WithLedger(ledger domain.LedgerRepository) Option {
+	return func(tg *TelegramBot) {
+		tg.ledger = ledger
+	}
+}
+
+func WithOutbox(outbox domain.OutboxRepository) Option {
+	return func(tg *TelegramBot) {
+		tg.outbox = outbox
+	}
+}
+
+func WithFingerprints(fingerprints domain.FingerprintRepository) Option {
+	return func(tg *TelegramBot) {
+		tg.fingerprints = fingerprints
+	}
+}
+
+func WithRules(rules domain.RuleRepository) Option {
+	return func(tg *TelegramBot) {
+		tg.rules = rules
+	}
+}
+
+func WithCategories(categories domain.CategoryRepository) Option {
+	return func(tg *TelegramBot) {
+		tg.categories = categories
+	}
+}
+
+func WithBudgets(budgets domain.BudgetRepository) Option {
+	return func(tg *TelegramBot) {
+		tg.budgets = budgets
+	}
+}
+
+func WithBalances(balances domain.BalanceRepository) Option {
+	return func(tg *TelegramBot) {
+		tg.balances = balances
+	}
+}
+
+func WithRates(rates domain.RatesProvider) Option {
+	return func(tg *TelegramBot) {
+		tg.rates = rates
+	}
+}
+
+func WithSinks(names ...string) Option {
+	return func(tg *TelegramBot) {
+		tg.sinks = names
+	}
+}
+
 type TelegramBotMessageEntity telebot.MessageEntity
 
 func (e TelegramBotMessageEntity) IsCommand() bool {
@@ -127,7 +236,7 @@ func NewPoller(timeoutSec time.Duration) *telebot.MiddlewarePoller {
 			msg := TelegramBotMessage(*upd.Message)
 			if msg.IsCommand() {
 				switch msg.Command() {
-				case "main", "start", "addgoogletoken", "setsheet", "setsheetlist", "setpatterns", "cancel":
+				case "main", "start", "addgoogletoken", "authmode", "setsheet", "setsheetlist", "createsheet", "setpatterns", "patternsettings", "presets", "numberformat", "timezone", "basecurrency", "testpattern", "duplicates", "pending", "rules", "addrule", "report", "export", "digest", "budget", "balance", "cancel":
 				default:
 					upd.Message.Text = endpointCommandNotFound
 				}
@@ -139,13 +248,22 @@ func NewPoller(timeoutSec time.Duration) *telebot.MiddlewarePoller {
 	return middlewarePoller
 }
 
-func NewTelegramBot(bot *telebot.Bot, userRepo domain.UserRepository, trxClient *store.GoogleClient) *TelegramBot {
+func NewTelegramBot(bot *telebot.Bot, userRepo domain.UserRepository, trxClient *store.GoogleClient, opts ...Option) *TelegramBot {
 
 	instance := &TelegramBot{
 		bot:       bot,
 		userRepo:  userRepo,
 		trxClient: trxClient,
+		sinks:     []string{SinkGoogle},
 		sessions:  map[int]sessionBot{},
+		patterns:  newPatternCache(),
+		recent:    newRecentTransactions(),
+
+		oauthStates: newOAuthStates(),
+	}
+
+	for _, opt := range opts {
+		opt(instance)
 	}
 
 	instance.startSelector = instance.newStartSelector(bot)
@@ -157,19 +275,73 @@ func NewTelegramBot(bot *telebot.Bot, userRepo domain.UserRepository, trxClient
 	mainCommand.AddBotMessageHandle(instance, instance.startHandler)
 
 	addGoogleTokenCommand.AddBotMessageHandle(instance, instance.addGoogleTokenHandler)
+	authModeCommand.AddBotMessageHandle(instance, instance.authModeHandler)
 	setSheetCommand.AddBotMessageHandle(instance, instance.setSheetHandler)
 	setSheetListCommand.AddBotMessageHandle(instance, instance.setSheetListHandler)
+	createSheetCommand.AddBotMessageHandle(instance, instance.createSheetHandler)
 	setPatternsCommand.AddBotMessageHandle(instance, instance.setPatternsHandler)
+	patternSettingsCommand.AddBotMessageHandle(instance, instance.patternSettingsHandler)
+	presetsCommand.AddBotMessageHandle(instance, instance.presetsHandler)
+	numberFormatCommand.AddBotMessageHandle(instance, instance.numberFormatHandler)
+	timezoneCommand.AddBotMessageHandle(instance, instance.timezoneHandler)
+	baseCurrencyCommand.AddBotMessageHandle(instance, instance.baseCurrencyHandler)
+	testPatternCommand.AddBotMessageHandle(instance, instance.testPatternHandler)
+	duplicatesCommand.AddBotMessageHandle(instance, instance.duplicatesHandler)
+	pendingCommand.AddBotMessageHandle(instance, instance.pendingHandler)
+	rulesCommand.AddBotMessageHandle(instance, instance.rulesHandler)
+	addRuleCommand.AddBotMessageHandle(instance, instance.addRuleHandler)
+	reportCommand.AddBotMessageHandle(instance, instance.reportHandler)
+	exportCommand.AddBotMessageHandle(instance, instance.exportHandler)
+	digestCommand.AddBotMessageHandle(instance, instance.digestHandler)
+	budgetCommand.AddBotMessageHandle(instance, instance.budgetHandler)
+	balanceCommand.AddBotMessageHandle(instance, instance.balanceHandler)
 	cancelCommand.AddBotMessageHandle(instance, instance.cancelHandler)
 
+	bot.Handle(&btnOutboxRetry, instance.outboxRetryCallback)
+	bot.Handle(&btnGoogleManual, instance.googleManualCallback)
+	bot.Handle(&btnOutboxDrop, instance.outboxDropCallback)
+	bot.Handle(&btnPreset, instance.presetCallback)
+	bot.Handle(&btnRuleAdd, func(c *telebot.Callback) {
+		addRuleCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
+	bot.Handle(&btnRuleDelete, instance.ruleDeleteCallback)
+	bot.Handle(&btnCategory, instance.categoryCallback)
+	bot.Handle(&btnTransactionEdit, instance.transactionEditCallback)
+	bot.Handle(&btnTransactionUndo, instance.transactionUndoCallback)
+	bot.Handle(&btnReport, instance.reportCallback)
+	bot.Handle(&btnExportFormat, instance.exportFormatCallback)
+	bot.Handle(&btnExportPeriod, instance.exportPeriodCallback)
+	bot.Handle(&btnDigest, instance.digestCallback)
+	bot.Handle(&btnBudgetAdd, instance.budgetAddCallback)
+	bot.Handle(&btnBudgetDelete, instance.budgetDeleteCallback)
+	bot.Handle(&btnAuthMode, instance.authModeCallback)
+	bot.Handle(&btnSheetCreate, instance.createSheetCallback)
+
 	bot.Handle(telebot.OnText, instance.onTextHandler)
 
 	_ = instance.setCommands(
 		mainCommand,
 		addGoogleTokenCommand,
+		authModeCommand,
 		setSheetCommand,
 		setSheetListCommand,
+		createSheetCommand,
 		setPatternsCommand,
+		patternSettingsCommand,
+		presetsCommand,
+		numberFormatCommand,
+		timezoneCommand,
+		baseCurrencyCommand,
+		testPatternCommand,
+		duplicatesCommand,
+		pendingCommand,
+		rulesCommand,
+		addRuleCommand,
+		reportCommand,
+		exportCommand,
+		digestCommand,
+		budgetCommand,
+		balanceCommand,
 		cancelCommand,
 	)
 
@@ -179,28 +351,103 @@ func NewTelegramBot(bot *telebot.Bot, userRepo domain.UserRepository, trxClient
 func (tg *TelegramBot) newStartSelector(bot *telebot.Bot) *telebot.ReplyMarkup {
 	selector := &telebot.ReplyMarkup{}
 	btnAddGoogleToken := selector.Data("Add Google Token", "addGoogleToken")
+	btnAuthModeMenu := selector.Data("Google auth mode", "authModeMenu")
 	btnSetSheet := selector.Data("Set Sheet ID", "setSheet")
 	btnSetSheetList := selector.Data("Set Sheet List", "setSheetList")
+	btnCreateSheet := selector.Data("Create sheet for me", "createSheet")
 	btnSetPatternsList := selector.Data("Set Patterns for parser", "setPatterns")
+	btnPatternSettings := selector.Data("Patterns settings", "patternSettings")
+	btnPresets := selector.Data("Patterns library", "presets")
+	btnNumberFormat := selector.Data("Number format", "numberFormat")
+	btnTimezone := selector.Data("Timezone", "timezone")
+	btnBaseCurrency := selector.Data("Base currency", "baseCurrency")
+	btnTestPattern := selector.Data("Test patterns", "testPattern")
+	btnDuplicates := selector.Data("Toggle duplicates check", "duplicates")
+	btnRules := selector.Data("Categorization rules", "rules")
+	btnReportMenu := selector.Data("Spending report", "reportMenu")
+	btnExportMenu := selector.Data("Export", "exportMenu")
+	btnDigestMenu := selector.Data("Digest schedule", "digestMenu")
+	btnBudgetMenu := selector.Data("Budgets", "budgetMenu")
+	btnBalanceMenu := selector.Data("Balances", "balanceMenu")
 	selector.Inline(
 		selector.Row(btnAddGoogleToken),
+		selector.Row(btnAuthModeMenu),
 		selector.Row(btnSetSheet),
 		selector.Row(btnSetSheetList),
+		selector.Row(btnCreateSheet),
 		selector.Row(btnSetPatternsList),
+		selector.Row(btnPatternSettings),
+		selector.Row(btnPresets),
+		selector.Row(btnNumberFormat),
+		selector.Row(btnTimezone),
+		selector.Row(btnBaseCurrency),
+		selector.Row(btnTestPattern),
+		selector.Row(btnDuplicates),
+		selector.Row(btnRules),
+		selector.Row(btnReportMenu),
+		selector.Row(btnExportMenu),
+		selector.Row(btnDigestMenu),
+		selector.Row(btnBudgetMenu),
+		selector.Row(btnBalanceMenu),
 	)
 
 	bot.Handle(&btnAddGoogleToken, func(c *telebot.Callback) {
 		addGoogleTokenCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
 	})
+	bot.Handle(&btnAuthModeMenu, func(c *telebot.Callback) {
+		authModeCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
 	bot.Handle(&btnSetSheet, func(c *telebot.Callback) {
 		setSheetCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
 	})
 	bot.Handle(&btnSetSheetList, func(c *telebot.Callback) {
 		setSheetListCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
 	})
+	bot.Handle(&btnCreateSheet, func(c *telebot.Callback) {
+		createSheetCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
 	bot.Handle(&btnSetPatternsList, func(c *telebot.Callback) {
 		setPatternsCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
 	})
+	bot.Handle(&btnPatternSettings, func(c *telebot.Callback) {
+		patternSettingsCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
+	bot.Handle(&btnPresets, func(c *telebot.Callback) {
+		presetsCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
+	bot.Handle(&btnNumberFormat, func(c *telebot.Callback) {
+		numberFormatCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
+	bot.Handle(&btnTimezone, func(c *telebot.Callback) {
+		timezoneCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
+	bot.Handle(&btnBaseCurrency, func(c *telebot.Callback) {
+		baseCurrencyCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
+	bot.Handle(&btnTestPattern, func(c *telebot.Callback) {
+		testPatternCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
+	bot.Handle(&btnDuplicates, func(c *telebot.Callback) {
+		duplicatesCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
+	bot.Handle(&btnRules, func(c *telebot.Callback) {
+		rulesCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
+	bot.Handle(&btnReportMenu, func(c *telebot.Callback) {
+		reportCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
+	bot.Handle(&btnExportMenu, func(c *telebot.Callback) {
+		exportCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
+	bot.Handle(&btnDigestMenu, func(c *telebot.Callback) {
+		digestCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
+	bot.Handle(&btnBudgetMenu, func(c *telebot.Callback) {
+		budgetCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
+	bot.Handle(&btnBalanceMenu, func(c *telebot.Callback) {
+		balanceCommand.CallMessageHandler(&telebot.Message{Sender: c.Sender})
+	})
 
 	return selector
 }
@@ -214,11 +461,48 @@ func (tg *TelegramBot) setCommands(items ...telegramBotCommand) error {
 }
 
 func (tg *TelegramBot) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	tg.cancelWorkers = cancel
+
+	if tg.outbox != nil {
+		tg.workers.Add(1)
+		go func() {
+			defer tg.workers.Done()
+			tg.runOutbox(ctx)
+		}()
+	}
+
+	if tg.ledger != nil && tg.rates != nil {
+		tg.workers.Add(1)
+		go func() {
+			defer tg.workers.Done()
+			tg.runRatesBackfill(ctx)
+		}()
+	}
+
+	tg.workers.Add(1)
+	go func() {
+		defer tg.workers.Done()
+		tg.runDigests(ctx)
+	}()
+
+	if tg.oauthAddr != "" {
+		tg.workers.Add(1)
+		go func() {
+			defer tg.workers.Done()
+			tg.runOAuthServer(ctx)
+		}()
+	}
+
 	tg.bot.Start()
 }
 
 func (tg *TelegramBot) Stop() {
 	tg.bot.Stop()
+	if tg.cancelWorkers != nil {
+		tg.cancelWorkers()
+	}
+	tg.workers.Wait()
 }
 
 func (tg *TelegramBot) Send(to telebot.Recipient, what interface{}, options ...interface{}) error {
@@ -237,6 +521,15 @@ func (tg *TelegramBot) Send(to telebot.Recipient, what interface{}, options ...i
 	return nil
 }
 
+func (tg *TelegramBot) notifyUser(botUserID int, text string) {
+	if tg.bot == nil {
+		return
+	}
+	if err := tg.Send(&telebot.User{ID: botUserID}, text); err != nil {
+		log.Println("notify user: ", err)
+	}
+}
+
 func (tg *TelegramBot) forbiddenHandler(m *telebot.Message) {
 	_ = tg.Send(m.Sender, "Only private message!")
 }
@@ -255,16 +548,30 @@ func (tg *TelegramBot) startHandler(_ telegramBotCommand, m *telebot.Message) {
 		txt := fmt.Sprintf(`
 __Settings for: _%s_ __:
 
+\- *Google auth*: %s
 \- *Google token*: %s
 \- *Sheet ID*: %s
 \- *Sheet List*: %s
+\- *Monthly lists*: %s
 \- *Patterns*: %s
+\- *Duplicates check*: %s
+\- *Number format*: %s
+\- *Timezone*: %s
+\- *Base currency*: %s
+\- *Digest*: %s
 	`,
 			EscapeMarkdown2(m.Sender.Username),
+			EscapeMarkdown2(tg.formatAuthMode(user)),
 			IfThenElse(user.TokSheet == nil, "🚫", "✔"),
 			IfThenElse(user.SheetID == "", "🚫", "✔"),
 			IfThenElse(user.ListName == "", "🚫", "✔"),
+			IfThenElse(user.MonthlyList, "✔", "🚫"),
 			IfThenElse(user.TrxPatterns == nil, "🚫", "✔"),
+			IfThenElse(user.AllowDuplicates, "🚫", "✔"),
+			EscapeMarkdown2(formatNumberFormat(user.NumberFormat)),
+			EscapeMarkdown2(IfThenElse(user.Timezone == "", "UTC", user.Timezone).(string)),
+			EscapeMarkdown2(IfThenElse(user.BaseCurrency == "", "off", user.BaseCurrency).(string)),
+			EscapeMarkdown2(formatDigestSettings(user)),
 		)
 
 		return tg.Send(m.Sender, txt, tg.startSelector, telebot.ModeMarkdownV2)
@@ -278,9 +585,17 @@ func EscapeMarkdown2(in string) string {
 	return in
 }
 
-func (tg *TelegramBot) addGoogleTokenHandler(c telegramBotCommand, m *telebot.Message) {
+func (tg *TelegramBot) addGoogleTokenHandler(_ telegramBotCommand, m *telebot.Message) {
+	if tg.oauthRedirectURL != "" {
+		tg.sendGoogleRegistration(m)
+		return
+	}
+	tg.manualGoogleToken(m)
+}
+
+func (tg *TelegramBot) manualGoogleToken(m *telebot.Message) {
 	_ = tg.Send(m.Sender, tg.trxClient.NewRegistration())
-	tg.wrapperSession(m, c.Name, func(cancel context.CancelFunc) Step {
+	tg.wrapperSession(m, addGoogleTokenCommand.Name, func(cancel context.CancelFunc) Step {
 		return NewStep(func(ctx context.Context, sess *Session) error {
 			defer cancel()
 			return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
@@ -301,7 +616,7 @@ func (tg *TelegramBot) addGoogleTokenHandler(c telegramBotCommand, m *telebot.Me
 }
 
 func (tg *TelegramBot) setSheetHandler(c telegramBotCommand, m *telebot.Message) {
-	_ = tg.Send(m.Sender, "Please set google sheet id")
+	_ = tg.Send(m.Sender, fmt.Sprintf("Please set google sheet id or send /%s to create a new one", createSheetCommand.Command))
 	tg.wrapperSession(m, c.Name, func(cancel context.CancelFunc) Step {
 		return NewStep(func(ctx context.Context, sess *Session) error {
 			defer cancel()
@@ -342,10 +657,13 @@ func (tg *TelegramBot) setPatternsHandler(c telegramBotCommand, m *telebot.Messa
 			defer cancel()
 			return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
 				return tg.wrapperErr(msg, func() error {
-					err := tg.SaveRepoUserPatterns(msg.Sender.ID, strings.Split(strings.TrimSpace(msg.Text), "\n"))
+					warnings, err := tg.SaveRepoUserPatterns(msg.Sender.ID, strings.Split(strings.TrimSpace(msg.Text), "\n"))
 					if err != nil {
 						return err
 					}
+					if len(warnings) > 0 {
+						return tg.Send(msg.Sender, "Patterns: ✔\n\nWarnings:\n"+strings.Join(warnings, "\n"))
+					}
 					return tg.Send(msg.Sender, "Patterns: ✔")
 				})
 			})
@@ -353,6 +671,177 @@ func (tg *TelegramBot) setPatternsHandler(c telegramBotCommand, m *telebot.Messa
 	})
 }
 
+func (tg *TelegramBot) numberFormatHandler(c telegramBotCommand, m *telebot.Message) {
+	_ = tg.Send(m.Sender, `Please set number format of amounts:
+auto - detect separators
+en - 1,234.56
+de - 1.234,56
+ru - 1 234,56
+ch - 1'234.56
+or two characters: decimal and grouping separators, e.g. ",."`)
+	tg.wrapperSession(m, c.Name, func(cancel context.CancelFunc) Step {
+		return NewStep(func(ctx context.Context, sess *Session) error {
+			defer cancel()
+			return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+				return tg.wrapperErr(msg, func() error {
+					format, err := parseNumberFormat(msg.Text)
+					if err != nil {
+						return err
+					}
+					err = tg.SaveRepoUserNumberFormat(msg.Sender.ID, format)
+					if err != nil {
+						return err
+					}
+					return tg.Send(msg.Sender, fmt.Sprintf("Number format: %s ✔", formatNumberFormat(format)))
+				})
+			})
+		})
+	})
+}
+
+func (tg *TelegramBot) timezoneHandler(c telegramBotCommand, m *telebot.Message) {
+	_ = tg.Send(m.Sender, "Please set timezone, e.g. Europe/Moscow, Asia/Dubai or +04:00")
+	tg.wrapperSession(m, c.Name, func(cancel context.CancelFunc) Step {
+		return NewStep(func(ctx context.Context, sess *Session) error {
+			defer cancel()
+			return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+				return tg.wrapperErr(msg, func() error {
+					timezone := strings.TrimSpace(msg.Text)
+					err := tg.SaveRepoUserTimezone(msg.Sender.ID, timezone)
+					if err != nil {
+						return err
+					}
+					return tg.Send(msg.Sender, fmt.Sprintf("Timezone: %s ✔", timezone))
+				})
+			})
+		})
+	})
+}
+
+func (tg *TelegramBot) testPatternHandler(c telegramBotCommand, m *telebot.Message) {
+	_ = tg.Send(m.Sender, "Please send a sample message, nothing will be saved")
+	tg.wrapperSession(m, c.Name, func(cancel context.CancelFunc) Step {
+		return NewStep(func(ctx context.Context, sess *Session) error {
+			defer cancel()
+			return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+				return tg.wrapperErr(msg, func() error {
+					results, err := tg.TestPatterns(msg.Sender.ID, strings.TrimSpace(msg.Text))
+					if err != nil {
+						return err
+					}
+					return tg.Send(msg.Sender, formatPatternTestResults(results))
+				})
+			})
+		})
+	})
+}
+
+func formatPatternTestResults(results []PatternTestResult) string {
+	if len(results) == 0 {
+		return "Patterns not set."
+	}
+
+	var lines []string
+	var matched *PatternTestResult
+	for i, v := range results {
+		switch {
+		case v.Err != nil:
+			lines = append(lines, fmt.Sprintf("#%d %s: 🚫 %v", v.Index, v.Name, v.Err))
+		case v.Transaction == nil:
+			lines = append(lines, fmt.Sprintf("#%d %s: no match", v.Index, v.Name))
+		case matched == nil:
+			matched = &results[i]
+			lines = append(lines, fmt.Sprintf("#%d %s: ✔ match", v.Index, v.Name))
+		default:
+			lines = append(lines, fmt.Sprintf("#%d %s: ✔ match (not used)", v.Index, v.Name))
+		}
+	}
+
+	if matched == nil {
+		return strings.Join(lines, "\n") + "\n\nMessage would be skipped."
+	}
+
+	trans := matched.Transaction
+	return fmt.Sprintf(`%s
+
+Pattern #%d %s would save:
+- account: %s
+- party: %s
+- direction: %s
+- amount: %s
+- currency: %s
+- date: %s
+- total: %s`,
+		strings.Join(lines, "\n"),
+		matched.Index,
+		matched.Name,
+		trans.Account,
+		trans.Party,
+		trans.Direction,
+		trans.Amount.String(),
+		trans.Currency,
+		trans.Date.Format("02/01/2006 15:04"),
+		trans.Total.String(),
+	)
+}
+
+func (tg *TelegramBot) duplicatesHandler(_ telegramBotCommand, m *telebot.Message) {
+	_ = tg.wrapperErr(m, func() error {
+		user, err := tg.GetOrCreateRepoUser(m.Sender.ID)
+		if err != nil {
+			return err
+		}
+		err = tg.SaveRepoUserAllowDuplicates(m.Sender.ID, !user.AllowDuplicates)
+		if err != nil {
+			return err
+		}
+		return tg.Send(m.Sender, fmt.Sprintf("Duplicates check: %s", IfThenElse(user.AllowDuplicates, "✔", "🚫")))
+	})
+}
+
+func (tg *TelegramBot) patternSettingsHandler(c telegramBotCommand, m *telebot.Message) {
+	user, err := tg.GetRepoUser(m.Sender.ID)
+	if err != nil || len(user.TrxPatterns) == 0 {
+		_ = tg.Send(m.Sender, "Patterns not set.")
+		return
+	}
+	var lines []string
+	for i, v := range user.TrxPatterns {
+		lines = append(lines, fmt.Sprintf("#%d %s", i+1, v.Name))
+	}
+	_ = tg.Send(m.Sender, "Please send pattern number:\n"+strings.Join(lines, "\n"))
+
+	tg.wrapperSession(m, c.Name, func(cancel context.CancelFunc) Step {
+		return NewNextStep(func(ctx context.Context, sess *Session) error {
+			return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+				return tg.wrapperErr(msg, func() error {
+					index, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(msg.Text), "#"))
+					if err != nil || index < 1 || index > len(user.TrxPatterns) {
+						cancel()
+						return fmt.Errorf("pattern %q not found", msg.Text)
+					}
+					sess.AddValue(patternIndexKey, index)
+					return tg.Send(msg.Sender, fmt.Sprintf(
+						"Please send settings as key=value lines, empty value resets:\n\n%s",
+						formatPatternSettings(user.TrxPatterns[index-1])))
+				})
+			})
+		}, NewStep(func(ctx context.Context, sess *Session) error {
+			defer cancel()
+			return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
+				return tg.wrapperErr(msg, func() error {
+					index, _ := sess.Value(patternIndexKey).(int)
+					trxPattern, err := tg.SaveRepoUserPatternSettings(msg.Sender.ID, index, strings.TrimSpace(msg.Text))
+					if err != nil {
+						return err
+					}
+					return tg.Send(msg.Sender, fmt.Sprintf("Pattern #%d: ✔\n\n%s", index, formatPatternSettings(trxPattern)))
+				})
+			})
+		}))
+	})
+}
+
 func (tg *TelegramBot) cancelHandler(_ telegramBotCommand, m *telebot.Message) {
 	sb, ok := tg.sessions[m.Sender.ID]
 	if !ok {
@@ -367,11 +856,30 @@ func (tg *TelegramBot) onTextHandler(m *telebot.Message) {
 	_ = tg.runSession(m, NewSession(context.Background(), NewStep(func(ctx context.Context, sess *Session) error {
 		return tg.wrapperCtxMessage(ctx, func(msg *telebot.Message) error {
 			return tg.wrapperErr(msg, func() error {
-				ok, err := tg.ParseAndSaveMessage(msg.Sender.ID, strings.TrimSpace(msg.Text))
+				result, err := tg.ParseAndSaveMessage(msg.Sender.ID, strings.TrimSpace(msg.Text))
 				if err != nil {
 					return err
 				}
-				return tg.Send(msg.Sender, IfThenElse(ok, "Message save.", "Message skip."))
+				if result == nil {
+					return tg.Send(msg.Sender, "Message skip.")
+				}
+				if result.Duplicate {
+					return tg.Send(msg.Sender, "Already saved.")
+				}
+				txt := IfThenElse(result.Sinks.Saved(), "Message save.", "Message not saved.").(string) + formatSinkResults(result.Sinks)
+				if result.Transaction.Category != "" {
+					txt += fmt.Sprintf("\nCategory: %s", result.Transaction.Category)
+				}
+				if !result.Sinks.Saved() {
+					return tg.Send(msg.Sender, txt)
+				}
+				if err := tg.Send(msg.Sender, txt, tg.newSavedSelector(result.Transaction, result.Categories)); err != nil {
+					return err
+				}
+				for _, v := range result.Alerts {
+					_ = tg.Send(msg.Sender, v)
+				}
+				return nil
 			})
 		})
 	})))
@@ -417,6 +925,23 @@ func (tg *TelegramBot) wrapperSession(m *telebot.Message, name string, fn func(c
 	}
 }
 
+func formatSinkResults(results store.SinkResults) string {
+	txt := ""
+	for _, v := range results {
+		if v.Err != nil {
+			log.Printf("sink %s: %v", v.Name, v.Err)
+		}
+		if v.Queued {
+			txt += fmt.Sprintf("\n- %s: ⏳ queued for retry (/pending)", v.Name)
+		} else if v.Err != nil {
+			txt += fmt.Sprintf("\n- %s: 🚫 not saved", v.Name)
+		} else {
+			txt += fmt.Sprintf("\n- %s: ✔", v.Name)
+		}
+	}
+	return txt
+}
+
 func IfThenElse(condition bool, a interface{}, b interface{}) interface{} {
 	if condition {
 		return a
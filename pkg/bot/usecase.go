@@ -2,6 +2,8 @@ package bot
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
@@ -9,11 +11,11 @@ import (
 	"strings"
 	"time"
 
-	"github.com/shopspring/decimal"
-
+	"github.com/ftomza/go-bank-bot/pkg/rates"
 	"github.com/ftomza/go-bank-bot/pkg/store"
 
 	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -25,20 +27,45 @@ func (tg *TelegramBot) wrapperRepoUser(userID int, fn func(u domain.User) error)
 	return fn(user)
 }
 
-func (tg *TelegramBot) wrapperRepoUserAndRepoTrx(userID int, fn func(u domain.User, trx domain.TransactionRepository) error) error {
+func (tg *TelegramBot) wrapperRepoUserAndRepoTrx(userID int, fn func(u domain.User, trx *store.MultiTransactionRepository) error) error {
 	user, err := tg.GetRepoUser(userID)
 	if err != nil {
 		return err
 	}
-	client, err := tg.trxClient.Get(context.Background(), user.TokSheet)
-	if err != nil {
-		return err
+	return fn(user, store.NewMultiTransactionRepository(tg.userSinks(user)...))
+}
+
+func (tg *TelegramBot) userSinks(u domain.User) []store.TransactionSink {
+	var sinks []store.TransactionSink
+	for _, name := range tg.sinks {
+		repo, err := tg.newSinkRepo(name, u)
+		sinks = append(sinks, store.TransactionSink{Name: name, Repo: repo, Err: err})
 	}
-	trx, err := store.NewGoogleTransactionRepository(client, user.SheetID, user.ListName)
-	if err != nil {
-		return err
+	return sinks
+}
+
+func (tg *TelegramBot) newSinkRepo(name string, u domain.User) (domain.TransactionRepository, error) {
+	switch name {
+	case SinkGoogle:
+		client, err := tg.googleHTTPClient(u)
+		if err != nil {
+			return nil, err
+		}
+		opts := []store.GoogleRepositoryOption{store.WithGoogleLocation(userLocation(u))}
+		if u.MonthlyList {
+			opts = append(opts, store.WithGoogleMonthlyLists())
+		}
+		if u.Formatted {
+			opts = append(opts, store.WithGoogleFormattedLists())
+		}
+		return store.NewGoogleTransactionRepository(client, u.SheetID, u.ListName, opts...)
+	case SinkLedger:
+		if tg.ledger == nil {
+			return nil, errors.New("ledger not configured")
+		}
+		return tg.ledger, nil
 	}
-	return fn(user, trx)
+	return nil, fmt.Errorf("unknown sink %q", name)
 }
 
 func (tg *TelegramBot) GetRepoUser(userID int) (domain.User, error) {
@@ -62,55 +89,313 @@ func (tg *TelegramBot) GetOrCreateRepoUser(userID int) (domain.User, error) {
 func (tg *TelegramBot) SaveRepoUserGoogleToken(userID int, tok []byte) error {
 	return tg.wrapperRepoUser(userID, func(u domain.User) error {
 		u.TokSheet = tok
-		return tg.userRepo.Update(context.Background(), &u)
+		return tg.userRepo.UpdateFields(context.Background(), &u, "TokSheet")
 	})
 }
 
+func (tg *TelegramBot) revokeRepoUserGoogleToken(u domain.User, reason error) {
+	log.Printf("google token of user %d revoked: %v", u.ID, reason)
+	user, err := tg.GetRepoUser(u.BotUserID)
+	if err != nil {
+		log.Println("revoke google token: ", err)
+		return
+	}
+	if user.TokSheet == nil || !store.SameGoogleGrant(user.TokSheet, u.TokSheet) {
+		return
+	}
+	if err := tg.SaveRepoUserGoogleToken(u.BotUserID, nil); err != nil {
+		log.Println("revoke google token: ", err)
+		return
+	}
+	tg.notifyUser(u.BotUserID, fmt.Sprintf(
+		"⚠️ Google access was revoked or expired, transactions are not saved to the sheet.\nPlease send /%s to connect again.",
+		addGoogleTokenCommand.Command))
+}
+
 func (tg *TelegramBot) SaveRepoUserSheet(userID int, sheetID string) error {
 	return tg.wrapperRepoUser(userID, func(u domain.User) error {
+		if userAuthMode(u) == store.GoogleAuthServiceAccount {
+			if err := tg.checkServiceAccountSheet(u, sheetID); err != nil {
+				return err
+			}
+		}
 		u.SheetID = sheetID
-		return tg.userRepo.Update(context.Background(), &u)
+		u.MonthlyList = false
+		u.Formatted = false
+		return tg.userRepo.UpdateFields(context.Background(), &u, "SheetID", "MonthlyList", "Formatted")
 	})
 }
 
 func (tg *TelegramBot) SaveRepoUserSheetList(userID int, listName string) error {
 	return tg.wrapperRepoUser(userID, func(u domain.User) error {
 		u.ListName = listName
-		return tg.userRepo.Update(context.Background(), &u)
+		u.MonthlyList = false
+		u.Formatted = false
+		return tg.userRepo.UpdateFields(context.Background(), &u, "ListName", "MonthlyList", "Formatted")
 	})
 }
 
-func (tg *TelegramBot) SaveRepoUserPatterns(userID int, ptrs []string) error {
-	return tg.wrapperRepoUser(userID, func(u domain.User) error {
+func (tg *TelegramBot) SaveRepoUserPatterns(userID int, ptrs []string) ([]string, error) {
+	warnings, err := validatePatterns(ptrs)
+	if err != nil {
+		return warnings, err
+	}
+	return warnings, tg.wrapperRepoUser(userID, func(u domain.User) error {
+		existing := map[string]domain.TrxPattern{}
+		for _, v := range u.TrxPatterns {
+			if v.Preset == "" {
+				existing[v.Pattern] = v
+			}
+		}
 		var trxPatterns []domain.TrxPattern
 		for _, v := range ptrs {
-			trxPatterns = append(trxPatterns, domain.TrxPattern{Pattern: v})
+			if strings.TrimSpace(v) == "" {
+				continue
+			}
+			trxPattern, ok := existing[v]
+			if !ok {
+				trxPattern = domain.TrxPattern{Pattern: v, Name: fmt.Sprintf("Pattern %d", len(trxPatterns)+1)}
+			}
+			trxPatterns = append(trxPatterns, trxPattern)
+		}
+		for _, v := range u.TrxPatterns {
+			if v.Preset != "" {
+				trxPatterns = append(trxPatterns, v)
+			}
 		}
 		u.TrxPatterns = trxPatterns
-		return tg.userRepo.Update(context.Background(), &u)
+		tg.patterns.Invalidate(u.ID)
+		return tg.userRepo.UpdateFields(context.Background(), &u, "TrxPatterns")
 	})
 }
 
-func (tg *TelegramBot) ParseAndSaveMessage(userID int, msg string) (bool, error) {
-	ok := false
-	err := tg.wrapperRepoUserAndRepoTrx(userID, func(u domain.User, trx domain.TransactionRepository) error {
+func (tg *TelegramBot) ToggleRepoUserPreset(userID int, id string) (bool, error) {
+	preset, ok := findPreset(id)
+	if !ok {
+		return false, fmt.Errorf("preset %q not found", id)
+	}
+	enabled := true
+	err := tg.wrapperRepoUser(userID, func(u domain.User) error {
+		var trxPatterns []domain.TrxPattern
+		found := false
 		for _, v := range u.TrxPatterns {
-			if trans, err := prepareTransactionOfMessage(v.Pattern, msg); err != nil {
+			if v.Preset != id {
+				trxPatterns = append(trxPatterns, v)
+				continue
+			}
+			found = true
+			if v.Version < preset.Version {
+				trxPatterns = append(trxPatterns, preset.TrxPattern())
+			} else {
+				enabled = false
+			}
+		}
+		if !found {
+			trxPatterns = append(trxPatterns, preset.TrxPattern())
+		}
+		u.TrxPatterns = trxPatterns
+		tg.patterns.Invalidate(u.ID)
+		return tg.userRepo.UpdateFields(context.Background(), &u, "TrxPatterns")
+	})
+	return enabled, err
+}
+
+func (tg *TelegramBot) SaveRepoUserPatternSettings(userID int, index int, settings string) (domain.TrxPattern, error) {
+	var trxPattern domain.TrxPattern
+	err := tg.wrapperRepoUser(userID, func(u domain.User) error {
+		if index < 1 || index > len(u.TrxPatterns) {
+			return fmt.Errorf("pattern #%d not found", index)
+		}
+		trxPattern = u.TrxPatterns[index-1]
+		if err := parsePatternSettings(&trxPattern, settings); err != nil {
+			return err
+		}
+		u.TrxPatterns[index-1] = trxPattern
+		tg.patterns.Invalidate(u.ID)
+		return tg.userRepo.UpdateFields(context.Background(), &u, "TrxPatterns")
+	})
+	return trxPattern, err
+}
+
+func (tg *TelegramBot) SaveRepoUserNumberFormat(userID int, format *domain.NumberFormat) error {
+	return tg.wrapperRepoUser(userID, func(u domain.User) error {
+		u.NumberFormat = format
+		return tg.userRepo.UpdateFields(context.Background(), &u, "NumberFormat")
+	})
+}
+
+func (tg *TelegramBot) SaveRepoUserTimezone(userID int, timezone string) error {
+	if _, err := loadLocation(timezone); err != nil {
+		return err
+	}
+	return tg.wrapperRepoUser(userID, func(u domain.User) error {
+		u.Timezone = timezone
+		return tg.userRepo.UpdateFields(context.Background(), &u, "Timezone")
+	})
+}
+
+type ParseResult struct {
+	Transaction *domain.Transaction
+	Fingerprint string
+	Duplicate   bool
+	Sinks       store.SinkResults
+	Categories  []string
+	Alerts      []string
+}
+
+func (tg *TelegramBot) SaveRepoUserAllowDuplicates(userID int, allow bool) error {
+	return tg.wrapperRepoUser(userID, func(u domain.User) error {
+		u.AllowDuplicates = allow
+		return tg.userRepo.UpdateFields(context.Background(), &u, "AllowDuplicates")
+	})
+}
+
+func (tg *TelegramBot) ParseAndSaveMessage(userID int, msg string) (*ParseResult, error) {
+	var result *ParseResult
+	err := tg.wrapperRepoUserAndRepoTrx(userID, func(u domain.User, trx *store.MultiTransactionRepository) error {
+		for _, v := range tg.patterns.Get(u) {
+			if v.Err != nil {
+				log.Println("compile pattern: ", v.Err)
+				continue
+			}
+			if trans, err := prepareTransaction(v.Regexp, patternWithUserDefaults(v.Pattern, u), userLocation(u), msg); err != nil {
 				log.Println("prepare transaction of message: ", err)
 				return err
 			} else if trans != nil {
-				ok = true
-				return trx.Store(context.Background(), trans)
+				trans.ID = newTransactionID()
+				trans.UserID = u.ID
+				if err := tg.applyUserRules(u, trans); err != nil {
+					log.Println("apply rules: ", err)
+				}
+				if err := tg.applyLearnedCategory(u, trans); err != nil {
+					log.Println("apply learned category: ", err)
+				}
+				if err := tg.applyBaseCurrency(u, trans); err != nil {
+					log.Println("apply base currency: ", err)
+				}
+				result = &ParseResult{Transaction: trans, Fingerprint: transactionFingerprint(trans, messageHasDate(v.Regexp, msg))}
+				if err := tg.storeTransaction(u, trx, result); err != nil || result.Duplicate {
+					return err
+				}
+				if result.Categories, err = tg.suggestUserCategories(u, trans); err != nil {
+					log.Println("suggest categories: ", err)
+				}
+				if !result.Sinks.Saved() {
+					return nil
+				}
+				tg.recent.Put(recentTransaction{
+					Transaction: *trans,
+					Fingerprint: result.Fingerprint,
+					Categories:  result.Categories,
+				})
+				if warning, err := tg.trackBalance(u, trans); err != nil {
+					log.Println("track balance: ", err)
+				} else if warning != "" {
+					result.Alerts = append(result.Alerts, warning)
+				}
+				alerts, err := tg.checkBudgets(u, trans)
+				if err != nil {
+					log.Println("check budgets: ", err)
+				}
+				result.Alerts = append(result.Alerts, alerts...)
+				return nil
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (tg *TelegramBot) storeTransaction(u domain.User, trx *store.MultiTransactionRepository, result *ParseResult) error {
+	ctx := context.Background()
+	trans := result.Transaction
+
+	reserved := false
+	if tg.fingerprints != nil && !u.AllowDuplicates {
+		ok, err := tg.fingerprints.Reserve(ctx, u.ID, result.Fingerprint)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			result.Duplicate = true
+			return nil
+		}
+		reserved = true
+	}
+
+	result.Sinks = trx.StoreResults(ctx, trans)
+	tg.enqueueFailedSinks(u, trans, result.Sinks)
+
+	switch {
+	case tg.fingerprints == nil:
+		return nil
+	case !result.Sinks.Saved():
+		if reserved {
+			return tg.fingerprints.Delete(ctx, u.ID, result.Fingerprint)
+		}
+		return nil
+	case !reserved:
+		_, err := tg.fingerprints.Reserve(ctx, u.ID, result.Fingerprint)
+		return err
+	}
+	return nil
+}
+
+func messageHasDate(compRegEx *regexp.Regexp, msg string) bool {
+	return strings.TrimSpace(getParamsMsg(compRegEx, msg)["date"]) != ""
+}
+
+func transactionFingerprint(trans *domain.Transaction, dated bool) string {
+	date := ""
+	if dated {
+		date = trans.Date.UTC().Format(time.RFC3339)
+	}
+	hash := sha256.New()
+	for _, v := range []string{
+		trans.Account,
+		strings.ToUpper(strings.Join(strings.Fields(trans.Party), " ")),
+		trans.Direction,
+		trans.Amount.String(),
+		trans.Currency,
+		date,
+		trans.Total.String(),
+	} {
+		hash.Write([]byte(v))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+type PatternTestResult struct {
+	Index       int
+	Name        string
+	Pattern     string
+	Transaction *domain.Transaction
+	Err         error
+}
+
+func (tg *TelegramBot) TestPatterns(userID int, msg string) ([]PatternTestResult, error) {
+	var results []PatternTestResult
+	err := tg.wrapperRepoUser(userID, func(u domain.User) error {
+		for i, v := range tg.patterns.Get(u) {
+			result := PatternTestResult{
+				Index:   i + 1,
+				Name:    v.Pattern.Name,
+				Pattern: v.Pattern.Pattern,
+				Err:     v.Err,
 			}
+			if v.Err == nil {
+				result.Transaction, result.Err = prepareTransaction(v.Regexp, patternWithUserDefaults(v.Pattern, u), userLocation(u), msg)
+			}
+			results = append(results, result)
 		}
 		return nil
 	})
-	return ok, err
+	return results, err
 }
 
-func getParamsMsg(regEx, msg string) (paramsMap map[string]string) {
+func getParamsMsg(compRegEx *regexp.Regexp, msg string) (paramsMap map[string]string) {
 
-	var compRegEx = regexp.MustCompile(regEx)
 	match := compRegEx.FindStringSubmatch(msg)
 
 	paramsMap = make(map[string]string)
@@ -123,30 +408,43 @@ func getParamsMsg(regEx, msg string) (paramsMap map[string]string) {
 }
 
 func prepareTransactionOfMessage(pattern, msg string) (*domain.Transaction, error) {
-	params := getParamsMsg(pattern, msg)
+	return prepareTransactionOfPattern(domain.TrxPattern{Pattern: pattern}, msg)
+}
+
+func prepareTransactionOfPattern(pattern domain.TrxPattern, msg string) (*domain.Transaction, error) {
+	compRegEx, err := regexp.Compile(pattern.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	return prepareTransaction(compRegEx, pattern, time.UTC, msg)
+}
+
+func prepareTransaction(compRegEx *regexp.Regexp, pattern domain.TrxPattern, loc *time.Location, msg string) (*domain.Transaction, error) {
+	params := getParamsMsg(compRegEx, msg)
 
 	if len(params) == 0 {
 		return nil, nil
 	}
 
-	amount, err := decimal.NewFromString(strings.Replace(params["amount"], ",", "", -1))
+	amount, err := parseAmount(params["amount"], pattern.NumberFormat)
 	if err != nil {
 		return nil, err
 	}
 
-	total, _ := decimal.NewFromString(strings.Replace(params["total"], ",", "", -1))
+	total, _ := parseAmount(params["total"], pattern.NumberFormat)
 
-	date, err := parseDate(params["date"])
+	date, err := parseDate(strings.TrimSpace(params["date"]+" "+params["time"]), pattern.DateLayouts, loc)
 	if err != nil {
 		return nil, err
 	}
 
+	direction := valueOrDefault(params["direction"], pattern.Direction)
 	item := &domain.Transaction{
-		Account:   params["account"],
+		Account:   valueOrDefault(params["account"], pattern.Account),
 		Party:     params["party"],
-		Direction: params["direction"],
-		Amount:    amount,
-		Currency:  params["currency"],
+		Direction: direction,
+		Amount:    patternAmount(pattern, direction, amount),
+		Currency:  rates.NormalizeCurrency(valueOrDefault(params["currency"], pattern.Currency)),
 		Date:      date,
 		Total:     total,
 		Raw:       msg,
@@ -155,12 +453,44 @@ func prepareTransactionOfMessage(pattern, msg string) (*domain.Transaction, erro
 	return item, nil
 }
 
-func parseDate(text string) (time.Time, error) {
-	if text == "" {
-		return time.Now(), nil
+func patternAmount(pattern domain.TrxPattern, direction string, amount decimal.Decimal) decimal.Decimal {
+	switch {
+	case hasFold(pattern.Income, direction):
+		return amount.Abs()
+	case hasFold(pattern.Expense, direction):
+		return amount.Abs().Neg()
+	}
+	return amount
+}
+
+func hasFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+func patternWithUserDefaults(pattern domain.TrxPattern, u domain.User) domain.TrxPattern {
+	if pattern.NumberFormat == nil {
+		pattern.NumberFormat = u.NumberFormat
 	}
-	if strings.Count(text, "/") == 1 {
-		text = fmt.Sprintf("%s/%d", text, time.Now().Year())
+	return pattern
+}
+
+func userLocation(u domain.User) *time.Location {
+	loc, err := loadLocation(u.Timezone)
+	if err != nil {
+		log.Println("load user location: ", err)
+		return time.UTC
+	}
+	return loc
+}
+
+func valueOrDefault(value, def string) string {
+	if value == "" {
+		return def
 	}
-	return time.Parse("02/01/2006", text)
+	return value
 }
@@ -1,15 +1,24 @@
 package bot
 
 import (
+	"context"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/store"
 	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
 )
 
 func Test_prepareTransactionOfMessage(t *testing.T) {
+	defer func(fn func() time.Time) { timeNow = fn }(timeNow)
+	timeNow = func() time.Time { return time.Date(2020, 11, 1, 10, 0, 0, 0, time.UTC) }
+
 	type args struct {
 		pattern string
 		msg     string
@@ -52,3 +61,114 @@ func Test_prepareTransactionOfMessage(t *testing.T) {
 		})
 	}
 }
+
+func Test_transactionFingerprint(t *testing.T) {
+	item := domain.Transaction{
+		Account:  "5098",
+		Amount:   decimal.NewFromFloat(1123.33),
+		Currency: "AED",
+		Date:     time.Date(2020, 10, 31, 00, 00, 00, 00, time.UTC),
+		Total:    decimal.NewFromFloat(13274.59),
+		Raw:      "AED 1,123.33 is charged",
+	}
+	item.Party = "FACEBK  Ads"
+	same := item
+	same.Party = " facebk ads"
+	same.Raw = "AED 1,123.33 is charged, forwarded"
+	other := item
+	other.Total = decimal.NewFromFloat(12151.26)
+	later := item
+	later.Date = item.Date.AddDate(0, 0, 3)
+
+	if transactionFingerprint(&item, true) != transactionFingerprint(&same, true) {
+		t.Errorf("transactionFingerprint() must not depend on raw message or party spacing")
+	}
+	if transactionFingerprint(&item, true) == transactionFingerprint(&other, true) {
+		t.Errorf("transactionFingerprint() must depend on parsed fields")
+	}
+	if transactionFingerprint(&item, true) == transactionFingerprint(&later, true) {
+		t.Errorf("transactionFingerprint() must depend on a parsed date")
+	}
+	if transactionFingerprint(&item, false) != transactionFingerprint(&later, false) {
+		t.Errorf("transactionFingerprint() must ignore the date of a dateless message")
+	}
+}
+
+func Test_prepareTransactionOfPattern_defaults(t *testing.T) {
+	pattern := domain.TrxPattern{
+		Pattern:     `^Spent (?P<amount>[0-9.]+) at (?P<party>.+?) on (?P<date>[0-9.]+)$`,
+		DateLayouts: []string{"02.01.2006"},
+		Currency:    "EUR",
+		Direction:   "debit",
+		Account:     "Main card",
+	}
+	got, err := prepareTransactionOfPattern(pattern, "Spent 12.50 at CAFE on 05.11.2020")
+	if err != nil {
+		t.Fatalf("prepareTransactionOfPattern() error = %v", err)
+	}
+	want := &domain.Transaction{
+		Account:   "Main card",
+		Party:     "CAFE",
+		Direction: "debit",
+		Amount:    decimal.RequireFromString("12.50"),
+		Currency:  "EUR",
+		Date:      time.Date(2020, 11, 5, 0, 0, 0, 0, time.UTC),
+		Raw:       "Spent 12.50 at CAFE on 05.11.2020",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("prepareTransactionOfPattern() got = %v, want %v", got, want)
+	}
+
+	_, err = prepareTransactionOfPattern(pattern, "Spent 12.50 at CAFE on 2020.11.05")
+	if err == nil {
+		t.Errorf("prepareTransactionOfPattern() expected date layout error")
+	}
+}
+
+func Test_patternAmount(t *testing.T) {
+	pattern := domain.TrxPattern{Income: []string{"Пополнение", "credit"}, Expense: []string{"Покупка"}}
+	tests := []struct {
+		direction string
+		amount    string
+		want      string
+	}{
+		{direction: "Пополнение", amount: "5000", want: "5000"},
+		{direction: "CREDIT", amount: "-10", want: "10"},
+		{direction: "Покупка", amount: "12.5", want: "-12.5"},
+		{direction: "Перевод", amount: "-3", want: "-3"},
+		{direction: "", amount: "7", want: "7"},
+	}
+	for _, tt := range tests {
+		got := patternAmount(pattern, tt.direction, decimal.RequireFromString(tt.amount))
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("patternAmount(%q, %s) = %s, want %s", tt.direction, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func Test_revokeRepoUserGoogleToken(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:bot_revoke?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	require.NoError(t, err)
+	users := store.NewGormUserRepository(db)
+	require.NoError(t, users.Migration(context.Background()))
+
+	tg := &TelegramBot{userRepo: users}
+	require.NoError(t, users.Store(context.Background(), &domain.User{BotUserID: 42, TokSheet: []byte(`{"access_token":"a"}`), SheetID: "sheet"}))
+	user, err := tg.GetRepoUser(42)
+	require.NoError(t, err)
+
+	require.NoError(t, tg.SaveRepoUserGoogleToken(42, []byte(`{"access_token":"b"}`)))
+	tg.revokeRepoUserGoogleToken(user, store.ErrTokenRevoked)
+
+	fresh, err := tg.GetRepoUser(42)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{"access_token":"b"}`, string(fresh.TokSheet), "re-authorized token must survive")
+	}
+
+	tg.revokeRepoUserGoogleToken(fresh, store.ErrTokenRevoked)
+	user, err = tg.GetRepoUser(42)
+	if assert.NoError(t, err) {
+		assert.Nil(t, user.TokSheet)
+		assert.Equal(t, "sheet", user.SheetID)
+	}
+}
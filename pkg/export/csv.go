@@ -0,0 +1,23 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/store"
+)
+
+func WriteCSV(w io.Writer, items []domain.Transaction) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(store.GoogleTransactionColumns); err != nil {
+		return err
+	}
+	for i := range items {
+		if err := writer.Write(store.GoogleTransactionRecord(&items[i])); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
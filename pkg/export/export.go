@@ -0,0 +1,48 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+)
+
+const (
+	CSV  = "csv"
+	XLSX = "xlsx"
+	OFX  = "ofx"
+	QIF  = "qif"
+)
+
+var Formats = []string{CSV, XLSX, OFX, QIF}
+
+var mimeTypes = map[string]string{
+	CSV:  "text/csv",
+	XLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	OFX:  "application/x-ofx",
+	QIF:  "application/qif",
+}
+
+func Write(w io.Writer, format string, items []domain.Transaction) error {
+	switch format {
+	case CSV:
+		return WriteCSV(w, items)
+	case XLSX:
+		return WriteXLSX(w, items)
+	case OFX:
+		return WriteOFX(w, items)
+	case QIF:
+		return WriteQIF(w, items)
+	}
+	return fmt.Errorf("export: unknown format %q", format)
+}
+
+func MIME(format string) string {
+	return mimeTypes[format]
+}
+
+func FileName(format string, from, to time.Time) string {
+	return fmt.Sprintf("transactions_%s_%s.%s", from.Format("2006-01-02"), to.Format("2006-01-02"), strings.ToLower(format))
+}
@@ -0,0 +1,108 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fixtures() []domain.Transaction {
+	return []domain.Transaction{
+		{ID: "t1", Account: "5098", Party: "UBER <TRIP>", Direction: "debit", Amount: decimal.RequireFromString("-20.5"), Currency: "AED",
+			Date: time.Date(2020, 11, 1, 10, 0, 0, 0, time.UTC), Total: decimal.RequireFromString("979.5"), Raw: "uber, 20.5", Category: "Transport", Tags: []string{"taxi", "work"}},
+		{ID: "t2", Account: "5098", Party: "SALARY", Direction: "credit", Amount: decimal.RequireFromString("1000"), Currency: "AED",
+			Date: time.Date(2020, 11, 5, 9, 30, 0, 0, time.UTC), Total: decimal.RequireFromString("1979.5"), Raw: "salary"},
+	}
+}
+
+func Test_WriteCSV(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, Write(buf, CSV, fixtures()))
+	assert.Equal(t, `Account,Party,Direction,Amount,Currency,Date,Total,Raw,Category,Tags,ID
+5098,UBER <TRIP>,debit,-20.5,AED,2020-11-01T10:00:00Z,979.5,"uber, 20.5",Transport,"taxi, work",t1
+5098,SALARY,credit,1000,AED,2020-11-05T09:30:00Z,1979.5,salary,,,t2
+`, buf.String())
+}
+
+func Test_WriteXLSX(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, Write(buf, XLSX, fixtures()))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		files[f.Name] = string(body)
+	}
+	assert.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, files, "xl/workbook.xml")
+	sheet := files["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<c r="A1" t="inlineStr"><is><t xml:space="preserve">Account</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B2" t="inlineStr"><is><t xml:space="preserve">UBER &lt;TRIP&gt;</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="D2"><v>-20.5</v></c>`)
+	assert.Contains(t, sheet, `<c r="K3" t="inlineStr"><is><t xml:space="preserve">t2</t></is></c>`)
+}
+
+func Test_xlsxColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 10: "K", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		assert.Equal(t, want, xlsxColumn(i))
+	}
+}
+
+func Test_WriteOFX(t *testing.T) {
+	defer func(fn func() time.Time) { timeNow = fn }(timeNow)
+	timeNow = func() time.Time { return time.Date(2020, 12, 1, 8, 0, 0, 0, time.UTC) }
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, Write(buf, OFX, fixtures()))
+	txt := buf.String()
+	assert.True(t, strings.HasPrefix(txt, "OFXHEADER:100\n"))
+	assert.Contains(t, txt, "<OFX>\n<SIGNONMSGSRSV1>\n<SONRS>\n<STATUS>\n<CODE>0\n<SEVERITY>INFO\n</STATUS>\n"+
+		"<DTSERVER>20201201080000\n<LANGUAGE>ENG\n</SONRS>\n</SIGNONMSGSRSV1>\n<BANKMSGSRSV1>\n")
+	assert.Equal(t, 1, strings.Count(txt, "<STMTRS>"))
+	assert.Contains(t, txt, "<CURDEF>AED\n<BANKACCTFROM>\n<BANKID>0\n<ACCTID>5098\n")
+	assert.Contains(t, txt, "<STMTTRN>\n<TRNTYPE>DEBIT\n<DTPOSTED>20201101100000\n<TRNAMT>-20.50\n<FITID>t1\n<NAME>UBER &lt;TRIP&gt;\n<MEMO>Transport\n</STMTTRN>\n")
+	assert.Contains(t, txt, "<TRNTYPE>CREDIT\n<DTPOSTED>20201105093000\n<TRNAMT>1000.00\n")
+	assert.Contains(t, txt, "<LEDGERBAL>\n<BALAMT>1979.50\n<DTASOF>20201105093000\n</LEDGERBAL>")
+}
+
+func Test_WriteQIF(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, Write(buf, QIF, fixtures()))
+	assert.Equal(t, `!Type:Bank
+D11/01/2020
+T-20.50
+Nt1
+PUBER <TRIP>
+LTransport
+M5098 taxi work
+^
+D11/05/2020
+T1000.00
+Nt2
+PSALARY
+M5098
+^
+`, buf.String())
+}
+
+func Test_Write(t *testing.T) {
+	assert.Error(t, Write(&bytes.Buffer{}, "pdf", nil))
+	assert.Equal(t, "transactions_2020-11-01_2020-11-30.ofx",
+		FileName(OFX, time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 11, 30, 0, 0, 0, 0, time.UTC)))
+	for _, v := range Formats {
+		assert.NotEmpty(t, MIME(v), v)
+	}
+}
@@ -0,0 +1,108 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+)
+
+const ofxHeader = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:UTF-8
+CHARSET:NONE
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+`
+
+const ofxDateLayout = "20060102150405"
+
+var timeNow = time.Now
+
+func WriteOFX(w io.Writer, items []domain.Transaction) error {
+	var b strings.Builder
+	b.WriteString(ofxHeader)
+	b.WriteString("<OFX>\n<SIGNONMSGSRSV1>\n<SONRS>\n<STATUS>\n<CODE>0\n<SEVERITY>INFO\n</STATUS>\n")
+	fmt.Fprintf(&b, "<DTSERVER>%s\n<LANGUAGE>ENG\n</SONRS>\n</SIGNONMSGSRSV1>\n", timeNow().UTC().Format(ofxDateLayout))
+	b.WriteString("<BANKMSGSRSV1>\n")
+	for i, group := range groupByAccount(items) {
+		last := group[len(group)-1]
+		fmt.Fprintf(&b, "<STMTTRNRS>\n<TRNUID>%d\n<STATUS>\n<CODE>0\n<SEVERITY>INFO\n</STATUS>\n<STMTRS>\n", i+1)
+		fmt.Fprintf(&b, "<CURDEF>%s\n", ofxEscape(last.Currency))
+		fmt.Fprintf(&b, "<BANKACCTFROM>\n<BANKID>0\n<ACCTID>%s\n<ACCTTYPE>CHECKING\n</BANKACCTFROM>\n", ofxEscape(valueOrDefault(last.Account, "unknown")))
+		fmt.Fprintf(&b, "<BANKTRANLIST>\n<DTSTART>%s\n<DTEND>%s\n", group[0].Date.Format(ofxDateLayout), last.Date.Format(ofxDateLayout))
+		for _, v := range group {
+			b.WriteString("<STMTTRN>\n")
+			trnType := "CREDIT"
+			if v.Amount.IsNegative() {
+				trnType = "DEBIT"
+			}
+			fmt.Fprintf(&b, "<TRNTYPE>%s\n", trnType)
+			fmt.Fprintf(&b, "<DTPOSTED>%s\n", v.Date.Format(ofxDateLayout))
+			fmt.Fprintf(&b, "<TRNAMT>%s\n", v.Amount.StringFixed(2))
+			fmt.Fprintf(&b, "<FITID>%s\n", ofxEscape(transactionID(v)))
+			fmt.Fprintf(&b, "<NAME>%s\n", ofxEscape(truncate(v.Party, 32)))
+			if v.Category != "" {
+				fmt.Fprintf(&b, "<MEMO>%s\n", ofxEscape(v.Category))
+			}
+			b.WriteString("</STMTTRN>\n")
+		}
+		b.WriteString("</BANKTRANLIST>\n")
+		fmt.Fprintf(&b, "<LEDGERBAL>\n<BALAMT>%s\n<DTASOF>%s\n</LEDGERBAL>\n", last.Total.StringFixed(2), last.Date.Format(ofxDateLayout))
+		b.WriteString("</STMTRS>\n</STMTTRNRS>\n")
+	}
+	b.WriteString("</BANKMSGSRSV1>\n</OFX>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func groupByAccount(items []domain.Transaction) [][]domain.Transaction {
+	index := map[string]int{}
+	var groups [][]domain.Transaction
+	for _, v := range items {
+		key := v.Account + "|" + v.Currency
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], v)
+	}
+	return groups
+}
+
+func transactionID(trans domain.Transaction) string {
+	if trans.ID != "" {
+		return trans.ID
+	}
+	hash := sha256.Sum256([]byte(trans.Account + trans.Date.String() + trans.Amount.String() + trans.Raw))
+	return hex.EncodeToString(hash[:8])
+}
+
+func ofxEscape(v string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\n", " ").Replace(v)
+}
+
+func truncate(v string, n int) string {
+	runes := []rune(v)
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+	return v
+}
+
+func valueOrDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,33 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ftomza/go-bank-bot/domain"
+)
+
+func WriteQIF(w io.Writer, items []domain.Transaction) error {
+	var b strings.Builder
+	b.WriteString("!Type:Bank\n")
+	for _, v := range items {
+		fmt.Fprintf(&b, "D%s\n", v.Date.Format("01/02/2006"))
+		fmt.Fprintf(&b, "T%s\n", v.Amount.StringFixed(2))
+		fmt.Fprintf(&b, "N%s\n", qifLine(transactionID(v)))
+		fmt.Fprintf(&b, "P%s\n", qifLine(v.Party))
+		if v.Category != "" {
+			fmt.Fprintf(&b, "L%s\n", qifLine(v.Category))
+		}
+		if memo := strings.TrimSpace(strings.Join(append([]string{v.Account}, v.Tags...), " ")); memo != "" {
+			fmt.Fprintf(&b, "M%s\n", qifLine(memo))
+		}
+		b.WriteString("^\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func qifLine(v string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(v, "\r", " "), "\n", " ")
+}
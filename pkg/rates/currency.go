@@ -0,0 +1,38 @@
+package rates
+
+import (
+	"strings"
+)
+
+var currencySymbols = map[string]string{
+	"$":   "USD",
+	"US$": "USD",
+	"€":   "EUR",
+	"£":   "GBP",
+	"₽":   "RUB",
+	"РУБ": "RUB",
+	"Р":   "RUB",
+	"RUR": "RUB",
+	"₴":   "UAH",
+	"ГРН": "UAH",
+	"₸":   "KZT",
+	"ТГ":  "KZT",
+	"₹":   "INR",
+	"₺":   "TRY",
+	"¥":   "JPY",
+	"ZŁ":  "PLN",
+	"DHS": "AED",
+	"DH":  "AED",
+	"د.إ": "AED",
+}
+
+func NormalizeCurrency(text string) string {
+	code := strings.ToUpper(strings.TrimSpace(text))
+	if v, ok := currencySymbols[code]; ok {
+		return v
+	}
+	if v, ok := currencySymbols[strings.TrimSuffix(code, ".")]; ok {
+		return v
+	}
+	return code
+}
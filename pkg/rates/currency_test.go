@@ -0,0 +1,30 @@
+package rates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NormalizeCurrency(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "$", want: "USD"},
+		{text: " usd ", want: "USD"},
+		{text: "€", want: "EUR"},
+		{text: "₽", want: "RUB"},
+		{text: "руб", want: "RUB"},
+		{text: "руб.", want: "RUB"},
+		{text: "р.", want: "RUB"},
+		{text: "Dhs", want: "AED"},
+		{text: "AED", want: "AED"},
+		{text: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeCurrency(tt.text))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package rates
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
+)
+
+type FileProvider struct {
+	rates map[string][]domain.Rate
+}
+
+func NewFileProvider(path string) (*FileProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return newFileProvider(f)
+}
+
+func newFileProvider(r io.Reader) (*FileProvider, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	p := &FileProvider{rates: map[string][]domain.Rate{}}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(record[0], "date") {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", record[0])
+		if err != nil {
+			return nil, fmt.Errorf("rates/file: line %d: %w", line, err)
+		}
+		rate, err := decimal.NewFromString(record[3])
+		if err != nil {
+			return nil, fmt.Errorf("rates/file: line %d: %w", line, err)
+		}
+		item := domain.Rate{Base: strings.ToUpper(record[1]), Quote: strings.ToUpper(record[2]), Date: date, Rate: rate}
+		p.rates[item.Base+"/"+item.Quote] = append(p.rates[item.Base+"/"+item.Quote], item)
+	}
+	for _, v := range p.rates {
+		v := v
+		sort.Slice(v, func(i, j int) bool {
+			return v[i].Date.Before(v[j].Date)
+		})
+	}
+	return p, nil
+}
+
+func (p *FileProvider) Rate(_ context.Context, base, quote string, date time.Time) (decimal.Decimal, error) {
+	base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+	if rate, ok := p.find(base, quote, date); ok {
+		return rate, nil
+	}
+	if rate, ok := p.find(quote, base, date); ok && !rate.IsZero() {
+		return decimal.NewFromInt(1).Div(rate), nil
+	}
+	return decimal.Decimal{}, ErrRateNotFound
+}
+
+func (p *FileProvider) find(base, quote string, date time.Time) (decimal.Decimal, bool) {
+	items := p.rates[base+"/"+quote]
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	i := sort.Search(len(items), func(i int) bool {
+		return items[i].Date.After(day)
+	})
+	if i == 0 {
+		return decimal.Decimal{}, false
+	}
+	return items[i-1].Rate, true
+}
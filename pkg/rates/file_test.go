@@ -0,0 +1,55 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FileProvider(t *testing.T) {
+	p, err := newFileProvider(strings.NewReader(`date,base,quote,rate
+# monthly rates
+2020-11-01,USD,AED,3.6725
+2020-10-01,usd,aed,3.67
+2020-11-01,EUR,USD,1.25
+`))
+	require.NoError(t, err)
+
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2020, month, day, 12, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name    string
+		base    string
+		quote   string
+		date    time.Time
+		want    string
+		wantErr error
+	}{
+		{name: "exact", base: "USD", quote: "AED", date: date(11, 1), want: "3.6725"},
+		{name: "previous", base: "USD", quote: "AED", date: date(10, 31), want: "3.67"},
+		{name: "inverse", base: "USD", quote: "EUR", date: date(11, 15), want: "0.8"},
+		{name: "too early", base: "USD", quote: "AED", date: date(9, 30), wantErr: ErrRateNotFound},
+		{name: "unknown", base: "GBP", quote: "AED", date: date(11, 1), wantErr: ErrRateNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Rate(context.Background(), tt.base, tt.quote, tt.date)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "%v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, decimal.RequireFromString(tt.want).Equal(got), got.String())
+		})
+	}
+
+	_, err = newFileProvider(strings.NewReader("2020-11-01,USD,AED,x\n"))
+	assert.Error(t, err)
+}
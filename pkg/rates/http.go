@@ -0,0 +1,56 @@
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type HTTPProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHTTPProvider(baseURL string, client *http.Client) *HTTPProvider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPProvider{baseURL: strings.TrimSuffix(baseURL, "/"), client: client}
+}
+
+func (p *HTTPProvider) Rate(ctx context.Context, base, quote string, date time.Time) (decimal.Decimal, error) {
+	base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+	query := url.Values{"from": {base}, "to": {quote}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/"+date.Format("2006-01-02")+"?"+query.Encode(), nil)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return decimal.Decimal{}, ErrRateNotFound
+	} else if resp.StatusCode != http.StatusOK {
+		return decimal.Decimal{}, fmt.Errorf("rates/http: unexpected status %s", resp.Status)
+	}
+
+	var body struct {
+		Rates map[string]decimal.Decimal `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return decimal.Decimal{}, fmt.Errorf("rates/http: %w", err)
+	}
+	rate, ok := body.Rates[quote]
+	if !ok {
+		return decimal.Decimal{}, ErrRateNotFound
+	}
+	return rate, nil
+}
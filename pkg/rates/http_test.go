@@ -0,0 +1,38 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_HTTPProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2020-11-01" || r.URL.Query().Get("from") != "USD" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"amount":1.0,"base":"USD","date":"2020-10-30","rates":{"AED":3.6725}}`))
+	}))
+	defer srv.Close()
+
+	p := NewHTTPProvider(srv.URL+"/", srv.Client())
+	date := time.Date(2020, 11, 1, 10, 0, 0, 0, time.UTC)
+
+	got, err := p.Rate(context.Background(), "usd", "aed", date)
+	if assert.NoError(t, err) {
+		assert.True(t, decimal.RequireFromString("3.6725").Equal(got), got.String())
+	}
+
+	_, err = p.Rate(context.Background(), "USD", "EUR", date)
+	assert.True(t, errors.Is(err, ErrRateNotFound), "%v", err)
+
+	_, err = p.Rate(context.Background(), "EUR", "AED", date)
+	assert.True(t, errors.Is(err, ErrRateNotFound), "%v", err)
+}
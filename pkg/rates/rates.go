@@ -0,0 +1,103 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var ErrRateNotFound = errors.New("rates: rate not found")
+
+const maxRateAge = 4 * 24 * time.Hour
+
+func Convert(ctx context.Context, provider domain.RatesProvider, amount decimal.Decimal, from, to string, date time.Time) (decimal.Decimal, error) {
+	if strings.EqualFold(from, to) {
+		return amount, nil
+	}
+	rate, err := provider.Rate(ctx, from, to, date)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("convert %s to %s: %w", from, to, err)
+	}
+	return amount.Mul(rate).Round(2), nil
+}
+
+type chainProvider []domain.RatesProvider
+
+func (c chainProvider) Rate(ctx context.Context, base, quote string, date time.Time) (decimal.Decimal, error) {
+	var errs []string
+	for _, v := range c {
+		rate, err := v.Rate(ctx, base, quote, date)
+		if err == nil {
+			return rate, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	if len(errs) == 0 {
+		return decimal.Decimal{}, ErrRateNotFound
+	}
+	return decimal.Decimal{}, fmt.Errorf("%w: %s", ErrRateNotFound, strings.Join(errs, "; "))
+}
+
+func NewChainProvider(providers ...domain.RatesProvider) domain.RatesProvider {
+	return chainProvider(providers)
+}
+
+type storeProvider struct {
+	repo domain.RateRepository
+	next domain.RatesProvider
+}
+
+func (s *storeProvider) Rate(ctx context.Context, base, quote string, date time.Time) (decimal.Decimal, error) {
+	base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+	rate, found, err := s.stored(ctx, base, quote, date)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	if found && rate.Date.Equal(rateDay(date)) {
+		return rate.Rate, nil
+	}
+
+	nextErr := ErrRateNotFound
+	if s.next != nil {
+		value, err := s.next.Rate(ctx, base, quote, date)
+		if err == nil {
+			return value, s.repo.Store(ctx, &domain.Rate{Base: base, Quote: quote, Date: date, Rate: value})
+		}
+		nextErr = err
+	}
+	if found && rateDay(date).Sub(rate.Date) <= maxRateAge {
+		return rate.Rate, nil
+	}
+	return decimal.Decimal{}, nextErr
+}
+
+func (s *storeProvider) stored(ctx context.Context, base, quote string, date time.Time) (domain.Rate, bool, error) {
+	rate, err := s.repo.Get(ctx, base, quote, date)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return rate, false, err
+	}
+	found := err == nil
+	inverse, err := s.repo.Get(ctx, quote, base, date)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return rate, false, err
+	}
+	if err == nil && !inverse.Rate.IsZero() && (!found || inverse.Date.After(rate.Date)) {
+		inverse.Rate = decimal.NewFromInt(1).Div(inverse.Rate)
+		return inverse, true, nil
+	}
+	return rate, found, nil
+}
+
+func rateDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func NewStoreProvider(repo domain.RateRepository, next domain.RatesProvider) domain.RatesProvider {
+	return &storeProvider{repo: repo, next: next}
+}
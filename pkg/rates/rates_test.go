@@ -0,0 +1,124 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/store"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type staticProvider struct {
+	rate  decimal.Decimal
+	calls int
+}
+
+func (p *staticProvider) Rate(_ context.Context, _, _ string, _ time.Time) (decimal.Decimal, error) {
+	p.calls++
+	if p.rate.IsZero() {
+		return decimal.Decimal{}, ErrRateNotFound
+	}
+	return p.rate, nil
+}
+
+func Test_Convert(t *testing.T) {
+	ctx := context.Background()
+	date := time.Date(2020, 11, 1, 10, 0, 0, 0, time.UTC)
+	p := &staticProvider{rate: decimal.RequireFromString("3.6725")}
+
+	got, err := Convert(ctx, p, decimal.RequireFromString("-10.5"), "USD", "AED", date)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "-38.56", got.String())
+	}
+
+	got, err = Convert(ctx, p, decimal.RequireFromString("10.555"), "aed", "AED", date)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "10.555", got.String())
+		assert.Equal(t, 1, p.calls, "same currency")
+	}
+
+	_, err = Convert(ctx, &staticProvider{}, decimal.NewFromInt(1), "USD", "AED", date)
+	assert.True(t, errors.Is(err, ErrRateNotFound))
+}
+
+func Test_NewChainProvider(t *testing.T) {
+	ctx := context.Background()
+	got, err := NewChainProvider(&staticProvider{}, &staticProvider{rate: decimal.NewFromInt(2)}).Rate(ctx, "USD", "AED", time.Now())
+	if assert.NoError(t, err) {
+		assert.Equal(t, "2", got.String())
+	}
+	_, err = NewChainProvider().Rate(ctx, "USD", "AED", time.Now())
+	assert.True(t, errors.Is(err, ErrRateNotFound))
+}
+
+func Test_NewStoreProvider(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open("file:rates_store?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	require.NoError(t, err)
+	repo := store.NewGormRateRepository(db)
+	require.NoError(t, repo.Migration(ctx))
+
+	date := time.Date(2020, 11, 1, 10, 0, 0, 0, time.UTC)
+	next := &staticProvider{rate: decimal.RequireFromString("0.8")}
+	p := NewStoreProvider(repo, next)
+
+	for i := 0; i < 2; i++ {
+		got, err := p.Rate(ctx, "usd", "EUR", date)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "0.8", got.String())
+		}
+	}
+	assert.Equal(t, 1, next.calls, "cached")
+
+	got, err := p.Rate(ctx, "EUR", "USD", date)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1.25", got.String())
+	}
+	assert.Equal(t, 1, next.calls, "inverse")
+
+	_, err = NewStoreProvider(repo, nil).Rate(ctx, "GBP", "USD", date)
+	assert.True(t, errors.Is(err, ErrRateNotFound))
+
+	saved, err := repo.Get(ctx, "USD", "EUR", date)
+	if assert.NoError(t, err) {
+		assert.Equal(t, domain.Rate{Base: "USD", Quote: "EUR", Date: time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC), Rate: saved.Rate}, saved)
+	}
+}
+
+func Test_NewStoreProvider_staleRate(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open("file:rates_store_stale?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	require.NoError(t, err)
+	repo := store.NewGormRateRepository(db)
+	require.NoError(t, repo.Migration(ctx))
+	require.NoError(t, repo.Store(ctx, &domain.Rate{Base: "USD", Quote: "EUR", Date: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Rate: decimal.RequireFromString("0.9")}))
+
+	next := &staticProvider{rate: decimal.RequireFromString("0.8")}
+	got, err := NewStoreProvider(repo, next).Rate(ctx, "USD", "EUR", time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "0.8", got.String())
+	}
+	assert.Equal(t, 1, next.calls, "stored rate is too old")
+
+	failing := &staticProvider{}
+	_, err = NewStoreProvider(repo, failing).Rate(ctx, "USD", "EUR", time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC))
+	assert.True(t, errors.Is(err, ErrRateNotFound))
+
+	got, err = NewStoreProvider(repo, failing).Rate(ctx, "USD", "EUR", time.Date(2020, 1, 4, 12, 0, 0, 0, time.UTC))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "0.9", got.String(), "recent rate is used when provider fails")
+	}
+	assert.Equal(t, 2, failing.calls)
+
+	got, err = NewStoreProvider(repo, nil).Rate(ctx, "EUR", "USD", time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC))
+	if assert.NoError(t, err) {
+		assert.Equal(t, decimal.NewFromInt(1).Div(decimal.RequireFromString("0.9")).String(), got.String())
+	}
+}
@@ -0,0 +1,203 @@
+package secret
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const KeySize = 32
+
+var envelopePrefix = []byte("enc:v1:")
+
+var ErrUnknownKey = errors.New("secret: unknown key")
+
+type envelope struct {
+	KeyID string `json:"kid"`
+	DEK   []byte `json:"dek"`
+	Data  []byte `json:"data"`
+}
+
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+func NewKeyring(keys ...[]byte) (*Keyring, error) {
+	k := &Keyring{keys: map[string][]byte{}}
+	for _, v := range keys {
+		if err := k.add(keyID(v), v); err != nil {
+			return nil, err
+		}
+	}
+	if k.primary == "" {
+		return nil, errors.New("secret: master key not set")
+	}
+	return k, nil
+}
+
+func ParseKeyring(text string) (*Keyring, error) {
+	k := &Keyring{keys: map[string][]byte{}}
+	for _, v := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		id, encoded := "", v
+		if i := strings.Index(v, ":"); i >= 0 {
+			id, encoded = v[:i], v[i+1:]
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("secret: decode key %q: %w", id, err)
+		}
+		if id == "" {
+			id = keyID(key)
+		}
+		if err := k.add(id, key); err != nil {
+			return nil, err
+		}
+	}
+	if k.primary == "" {
+		return nil, errors.New("secret: master key not set")
+	}
+	return k, nil
+}
+
+func LoadKeyring(value, path string) (*Keyring, error) {
+	if value == "" && path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		value = string(data)
+	}
+	return ParseKeyring(value)
+}
+
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+func (k *Keyring) add(id string, key []byte) error {
+	if len(key) != KeySize {
+		return fmt.Errorf("secret: key %q must be %d bytes, got %d", id, KeySize, len(key))
+	}
+	if _, ok := k.keys[id]; ok {
+		return fmt.Errorf("secret: duplicate key %q", id)
+	}
+	k.keys[id] = key
+	if k.primary == "" {
+		k.primary = id
+	}
+	return nil
+}
+
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+func (k *Keyring) Encrypted(data []byte) bool {
+	return bytes.HasPrefix(data, envelopePrefix)
+}
+
+func (k *Keyring) KeyID(data []byte) (string, error) {
+	env, err := k.open(data)
+	return env.KeyID, err
+}
+
+func (k *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	dek := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, err
+	}
+	data, err := seal(dek, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := seal(k.keys[k.primary], dek)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(envelope{KeyID: k.primary, DEK: wrapped, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, envelopePrefix...), body...), nil
+}
+
+func (k *Keyring) Decrypt(data []byte) ([]byte, error) {
+	env, err := k.open(data)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := k.keys[env.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, env.KeyID)
+	}
+	dek, err := unseal(key, env.DEK)
+	if err != nil {
+		return nil, fmt.Errorf("secret: unwrap data key: %w", err)
+	}
+	return unseal(dek, env.Data)
+}
+
+func (k *Keyring) open(data []byte) (envelope, error) {
+	env := envelope{}
+	if !k.Encrypted(data) {
+		return env, errors.New("secret: value is not encrypted")
+	}
+	if err := json.Unmarshal(data[len(envelopePrefix):], &env); err != nil {
+		return env, fmt.Errorf("secret: %w", err)
+	}
+	return env, nil
+}
+
+func keyID(key []byte) string {
+	hash := sha256.Sum256(key)
+	return hex.EncodeToString(hash[:4])
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func unseal(key, data []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("secret: ciphertext too short")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,113 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func Test_ParseKeyring(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(testKey(1))
+	k2 := base64.StdEncoding.EncodeToString(testKey(2))
+	tests := []struct {
+		name    string
+		text    string
+		primary string
+		wantErr bool
+	}{
+		{name: "single", text: k1, primary: keyID(testKey(1))},
+		{name: "with ids", text: "new:" + k2 + ",old:" + k1, primary: "new"},
+		{name: "lines", text: "\n" + k2 + "\n" + k1 + "\n", primary: keyID(testKey(2))},
+		{name: "empty", text: " ", wantErr: true},
+		{name: "short", text: base64.StdEncoding.EncodeToString([]byte("short")), wantErr: true},
+		{name: "bad base64", text: "k:***", wantErr: true},
+		{name: "duplicate", text: "a:" + k1 + ",a:" + k2, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKeyring(tt.text)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.primary, got.Primary())
+			}
+		})
+	}
+}
+
+func Test_LoadKeyring(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "master.key")
+	require.NoError(t, ioutil.WriteFile(path, []byte("file:"+base64.StdEncoding.EncodeToString(testKey(1))+"\n"), 0600))
+
+	k, err := LoadKeyring("", path)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "file", k.Primary())
+	}
+	k, err = LoadKeyring("env:"+base64.StdEncoding.EncodeToString(testKey(2)), path)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "env", k.Primary(), "environment wins")
+	}
+	_, err = LoadKeyring("", filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func Test_Keyring_EncryptDecrypt(t *testing.T) {
+	old, err := NewKeyring(testKey(1))
+	require.NoError(t, err)
+	rotated, err := NewKeyring(testKey(2), testKey(1))
+	require.NoError(t, err)
+
+	plaintext := []byte(`{"access_token":"a","refresh_token":"r"}`)
+	data, err := old.Encrypt(plaintext)
+	require.NoError(t, err)
+	assert.True(t, old.Encrypted(data))
+	assert.False(t, old.Encrypted(plaintext))
+	assert.NotContains(t, string(data), "refresh_token")
+
+	again, err := old.Encrypt(plaintext)
+	require.NoError(t, err)
+	assert.NotEqual(t, data, again, "random data key and nonce")
+
+	got, err := rotated.Decrypt(data)
+	if assert.NoError(t, err) {
+		assert.Equal(t, plaintext, got)
+	}
+	id, err := rotated.KeyID(data)
+	if assert.NoError(t, err) {
+		assert.Equal(t, old.Primary(), id)
+	}
+
+	data, err = rotated.Encrypt(plaintext)
+	require.NoError(t, err)
+	_, err = old.Decrypt(data)
+	assert.True(t, errors.Is(err, ErrUnknownKey), "%v", err)
+
+	tampered := append([]byte{}, data...)
+	tampered[len(tampered)-5] ^= 1
+	_, err = rotated.Decrypt(tampered)
+	assert.Error(t, err)
+
+	_, err = rotated.Decrypt(plaintext)
+	assert.Error(t, err)
+}
+
+func Test_GenerateKey(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+	k, err := ParseKeyring(key)
+	if assert.NoError(t, err) {
+		assert.NotEmpty(t, k.Primary())
+	}
+}
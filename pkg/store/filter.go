@@ -0,0 +1,55 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/ftomza/go-bank-bot/domain"
+)
+
+var ErrTransactionNotFound = errors.New("store: transaction not found")
+
+func matchTransactionFilter(filter domain.TransactionFilter, item domain.Transaction) bool {
+	if !filter.From.IsZero() && item.Date.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !item.Date.Before(filter.To) {
+		return false
+	}
+	if filter.Account != "" && item.Account != filter.Account {
+		return false
+	}
+	if filter.Category != "" && item.Category != filter.Category {
+		return false
+	}
+	if filter.Direction != "" && item.Direction != filter.Direction {
+		return false
+	}
+	if filter.NoBaseCurrency && item.BaseCurrency != "" {
+		return false
+	}
+	if filter.Text != "" {
+		text := strings.ToLower(filter.Text)
+		if !strings.Contains(strings.ToLower(item.Party), text) && !strings.Contains(strings.ToLower(item.Raw), text) {
+			return false
+		}
+	}
+	return true
+}
+
+func filterTransactions(filter domain.TransactionFilter, items []domain.Transaction) []domain.Transaction {
+	var result []domain.Transaction
+	for _, v := range items {
+		if matchTransactionFilter(filter, v) {
+			result = append(result, v)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Date.Before(result[j].Date)
+	})
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result
+}
@@ -4,10 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
 	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
 
 	"google.golang.org/api/option"
 
@@ -28,6 +34,16 @@ func (r *GoogleClient) NewRegistration() string {
 	return r.config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
 }
 
+func (r *GoogleClient) NewStateRegistration(state string) string {
+	return r.config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+}
+
+func (r *GoogleClient) WithRedirectURL(redirectURL string) *GoogleClient {
+	config := *r.config
+	config.RedirectURL = redirectURL
+	return &GoogleClient{config: &config}
+}
+
 func (r *GoogleClient) GetToken(ctx context.Context, authCode string) ([]byte, error) {
 	tok, err := r.config.Exchange(ctx, authCode)
 	if err != nil {
@@ -36,23 +52,64 @@ func (r *GoogleClient) GetToken(ctx context.Context, authCode string) ([]byte, e
 	return json.Marshal(tok)
 }
 
-func (r *GoogleClient) Get(ctx context.Context, token []byte) (*http.Client, error) {
+func (r *GoogleClient) Get(ctx context.Context, token []byte, opts ...TokenOption) (*http.Client, error) {
 	tok := &oauth2.Token{}
 	err := json.Unmarshal(token, tok)
 	if err != nil {
 		return nil, err
 	}
-	return r.config.Client(ctx, tok), nil
+	src := &persistingTokenSource{base: r.config.TokenSource(ctx, tok), current: tok}
+	for _, opt := range opts {
+		opt(src)
+	}
+	return oauth2.NewClient(ctx, src), nil
 }
 
 type GoogleTransactionRepository struct {
-	srv      *sheets.Service
-	sheetID  string
-	listName string
+	srv       *sheets.Service
+	sheetID   string
+	listName  string
+	monthly   bool
+	formatted bool
+	location  *time.Location
+	ensured   map[string]bool
+}
+
+type GoogleRepositoryOption func(s *GoogleTransactionRepository)
+
+func WithGoogleLocation(loc *time.Location) GoogleRepositoryOption {
+	return func(s *GoogleTransactionRepository) {
+		if loc != nil {
+			s.location = loc
+		}
+	}
+}
+
+func WithGoogleMonthlyLists() GoogleRepositoryOption {
+	return func(s *GoogleTransactionRepository) {
+		s.monthly = true
+	}
+}
+
+func WithGoogleFormattedLists() GoogleRepositoryOption {
+	return func(s *GoogleTransactionRepository) {
+		s.formatted = true
+	}
+}
+
+func NewGoogleTransactionRepository(client *http.Client, sheetID, listName string, opts ...GoogleRepositoryOption) (domain.TransactionRepository, error) {
+	repo, err := newGoogleTransactionRepository(sheetID, listName, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(repo)
+	}
+	return repo, nil
 }
 
-func NewGoogleTransactionRepository(client *http.Client, sheetID, listName string) (domain.TransactionRepository, error) {
-	srv, err := sheets.NewService(context.Background(), option.WithHTTPClient(client))
+func newGoogleTransactionRepository(sheetID, listName string, opts ...option.ClientOption) (*GoogleTransactionRepository, error) {
+	srv, err := sheets.NewService(context.Background(), opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -65,24 +122,393 @@ func NewGoogleTransactionRepository(client *http.Client, sheetID, listName strin
 		srv:      srv,
 		sheetID:  sheetID,
 		listName: listName,
+		location: time.UTC,
+		ensured:  map[string]bool{},
 	}, nil
 }
 
+const (
+	googleRefKey     = "google"
+	googleLastColumn = "K"
+	googleRender     = "UNFORMATTED_VALUE"
+	googleDateRender = "SERIAL_NUMBER"
+	googleValueInput = "USER_ENTERED"
+)
+
+var (
+	googleRowRx       = regexp.MustCompile(`[A-Z]+(\d+)(?::[A-Z]+\d+)?$`)
+	googleSerialEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+)
+
 func (s *GoogleTransactionRepository) Store(ctx context.Context, item *domain.Transaction) error {
+	list := s.listFor(item.Date)
+	if s.monthly && !s.ensured[list] {
+		if err := (&GoogleSpreadsheet{srv: s.srv}).EnsureList(ctx, s.sheetID, list); err != nil {
+			return err
+		}
+		s.ensured[list] = true
+	}
 
-	valueInputOption := "RAW"
 	insertDataOption := "INSERT_ROWS"
 	rb := &sheets.ValueRange{
 		Values: [][]interface{}{
-			{item.Account, item.Party, item.Direction, item.Amount, item.Currency, item.Date, item.Total, item.Raw},
+			s.row(item),
 		},
 	}
 	resp, err := s.srv.Spreadsheets.Values.
-		Append(s.sheetID, s.listName, rb).
-		ValueInputOption(valueInputOption).
+		Append(s.sheetID, googleA1Range(list, "A1"), rb).
+		ValueInputOption(googleValueInput).
 		InsertDataOption(insertDataOption).
 		Context(ctx).
 		Do()
-	log.Println(resp)
+	if err != nil {
+		return err
+	}
+	if resp.Updates != nil && resp.Updates.UpdatedRange != "" {
+		if item.Refs == nil {
+			item.Refs = map[string]string{}
+		}
+		item.Refs[googleRefKey] = resp.Updates.UpdatedRange
+	}
+	return nil
+}
+
+func (s *GoogleTransactionRepository) Update(ctx context.Context, item *domain.Transaction) error {
+	list, row, err := s.findRow(ctx, item)
+	if err != nil {
+		return err
+	}
+	if s.monthly && list != s.listFor(item.Date) {
+		if err := s.deleteRow(ctx, list, row); err != nil {
+			return err
+		}
+		delete(item.Refs, googleRefKey)
+		return s.Store(ctx, item)
+	}
+	rb := &sheets.ValueRange{
+		Values: [][]interface{}{
+			s.row(item),
+		},
+	}
+	_, err = s.srv.Spreadsheets.Values.
+		Update(s.sheetID, googleA1Range(list, fmt.Sprintf("A%d:%s%d", row, googleLastColumn, row)), rb).
+		ValueInputOption(googleValueInput).
+		Context(ctx).
+		Do()
 	return err
 }
+
+func (s *GoogleTransactionRepository) Delete(ctx context.Context, item *domain.Transaction) error {
+	list, row, err := s.findRow(ctx, item)
+	if err != nil {
+		return err
+	}
+	return s.deleteRow(ctx, list, row)
+}
+
+func (s *GoogleTransactionRepository) deleteRow(ctx context.Context, list string, row int) error {
+	sheetID, err := s.findSheetID(ctx, list)
+	if err != nil {
+		return err
+	}
+	_, err = s.srv.Spreadsheets.BatchUpdate(s.sheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{{
+			DeleteDimension: &sheets.DeleteDimensionRequest{
+				Range: &sheets.DimensionRange{
+					SheetId:         sheetID,
+					Dimension:       "ROWS",
+					StartIndex:      int64(row - 1),
+					EndIndex:        int64(row),
+					ForceSendFields: []string{"SheetId", "StartIndex"},
+				},
+			},
+		}},
+	}).Context(ctx).Do()
+	return err
+}
+
+func (s *GoogleTransactionRepository) Get(ctx context.Context, userID uint, id string) (domain.Transaction, error) {
+	lists, err := s.lists(ctx, time.Time{}, time.Time{})
+	if err != nil {
+		return domain.Transaction{}, err
+	}
+	for _, list := range lists {
+		items, err := s.rows(ctx, list)
+		if err != nil {
+			return domain.Transaction{}, err
+		}
+		for _, v := range items {
+			if id != "" && v.ID == id {
+				v.UserID = userID
+				return v, nil
+			}
+		}
+	}
+	return domain.Transaction{}, ErrTransactionNotFound
+}
+
+func (s *GoogleTransactionRepository) List(ctx context.Context, filter domain.TransactionFilter) ([]domain.Transaction, error) {
+	lists, err := s.lists(ctx, filter.From, filter.To)
+	if err != nil {
+		return nil, err
+	}
+	var items []domain.Transaction
+	for _, list := range lists {
+		rows, err := s.rows(ctx, list)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, rows...)
+	}
+	for i := range items {
+		items[i].UserID = filter.UserID
+	}
+	return filterTransactions(filter, items), nil
+}
+
+func (s *GoogleTransactionRepository) listFor(date time.Time) string {
+	if !s.monthly {
+		return s.listName
+	}
+	return GoogleMonthlyList(date.In(s.location))
+}
+
+func (s *GoogleTransactionRepository) lists(ctx context.Context, from, to time.Time) ([]string, error) {
+	if !s.monthly {
+		return []string{s.listName}, nil
+	}
+	resp, err := s.srv.Spreadsheets.Get(s.sheetID).Fields("sheets.properties").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	var lists []string
+	for _, v := range resp.Sheets {
+		if v.Properties == nil {
+			continue
+		}
+		month, err := time.ParseInLocation(googleMonthListLayout, v.Properties.Title, s.location)
+		if err != nil {
+			continue
+		}
+		if (!from.IsZero() && !month.AddDate(0, 1, 0).After(from)) || (!to.IsZero() && !month.Before(to)) {
+			continue
+		}
+		lists = append(lists, v.Properties.Title)
+	}
+	sort.Strings(lists)
+	return lists, nil
+}
+
+func (s *GoogleTransactionRepository) rows(ctx context.Context, list string) ([]domain.Transaction, error) {
+	resp, err := s.srv.Spreadsheets.Values.
+		Get(s.sheetID, googleA1Range(list, "A:"+googleLastColumn)).
+		ValueRenderOption(googleRender).
+		DateTimeRenderOption(googleDateRender).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, err
+	}
+	var items []domain.Transaction
+	for i, v := range resp.Values {
+		item, ok := googleRowTransaction(v, s.location)
+		if !ok {
+			continue
+		}
+		item.Refs = map[string]string{googleRefKey: googleA1Range(list, fmt.Sprintf("A%d:%s%d", i+1, googleLastColumn, i+1))}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (s *GoogleTransactionRepository) findRow(ctx context.Context, item *domain.Transaction) (string, int, error) {
+	if item.ID == "" {
+		return "", 0, errors.New("sheet/google: transaction id not set")
+	}
+	list, ok := googleRefList(item.Refs[googleRefKey])
+	if !ok {
+		list = s.listFor(item.Date)
+	}
+	if match := googleRowRx.FindStringSubmatch(item.Refs[googleRefKey]); match != nil {
+		row, _ := strconv.Atoi(match[1])
+		cell := googleA1Range(list, fmt.Sprintf("%s%d", googleLastColumn, row))
+		resp, err := s.srv.Spreadsheets.Values.Get(s.sheetID, cell).ValueRenderOption(googleRender).Context(ctx).Do()
+		if err != nil {
+			return "", 0, err
+		}
+		if len(resp.Values) > 0 && len(resp.Values[0]) > 0 && fmt.Sprint(resp.Values[0][0]) == item.ID {
+			return list, row, nil
+		}
+	}
+
+	candidates := []string{list}
+	if s.monthly {
+		lists, err := s.lists(ctx, time.Time{}, time.Time{})
+		if err != nil {
+			return "", 0, err
+		}
+		candidates = lists
+		for i, v := range lists {
+			if v == list {
+				candidates = append([]string{list}, append(lists[:i:i], lists[i+1:]...)...)
+				break
+			}
+		}
+	}
+	for _, list := range candidates {
+		column := googleA1Range(list, googleLastColumn+":"+googleLastColumn)
+		resp, err := s.srv.Spreadsheets.Values.Get(s.sheetID, column).ValueRenderOption(googleRender).Context(ctx).Do()
+		if err != nil {
+			return "", 0, err
+		}
+		for i, v := range resp.Values {
+			if len(v) > 0 && fmt.Sprint(v[0]) == item.ID {
+				return list, i + 1, nil
+			}
+		}
+	}
+	return "", 0, ErrTransactionNotFound
+}
+
+func (s *GoogleTransactionRepository) findSheetID(ctx context.Context, list string) (int64, error) {
+	resp, err := s.srv.Spreadsheets.Get(s.sheetID).Fields("sheets.properties").Context(ctx).Do()
+	if err != nil {
+		return 0, err
+	}
+	for i, v := range resp.Sheets {
+		if v.Properties == nil {
+			continue
+		}
+		if v.Properties.Title == list || (list == "" && i == 0) {
+			return v.Properties.SheetId, nil
+		}
+	}
+	return 0, fmt.Errorf("sheet/google: list %q not found", list)
+}
+
+func googleA1Range(list, cells string) string {
+	if list == "" {
+		return cells
+	}
+	return "'" + strings.ReplaceAll(list, "'", "''") + "'!" + cells
+}
+
+func googleRefList(ref string) (string, bool) {
+	i := strings.LastIndex(ref, "!")
+	if i == -1 {
+		return "", false
+	}
+	list := ref[:i]
+	if len(list) >= 2 && strings.HasPrefix(list, "'") && strings.HasSuffix(list, "'") {
+		list = strings.ReplaceAll(list[1:len(list)-1], "''", "'")
+	}
+	return list, true
+}
+
+func googleSerial(date time.Time, loc *time.Location) float64 {
+	date = date.In(loc)
+	wall := time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), time.UTC)
+	return float64(wall.Sub(googleSerialEpoch)) / float64(24*time.Hour)
+}
+
+func googleSerialTime(serial float64, loc *time.Location) time.Time {
+	wall := googleSerialEpoch.Add(time.Duration(serial * float64(24*time.Hour))).Round(time.Millisecond)
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), loc)
+}
+
+var GoogleTransactionColumns = []string{
+	"Account", "Party", "Direction", "Amount", "Currency", "Date", "Total", "Raw", "Category", "Tags", "ID",
+}
+
+func GoogleTransactionRecord(item *domain.Transaction) []string {
+	return []string{
+		item.Account,
+		item.Party,
+		item.Direction,
+		item.Amount.String(),
+		item.Currency,
+		item.Date.Format(time.RFC3339Nano),
+		item.Total.String(),
+		item.Raw,
+		item.Category,
+		strings.Join(item.Tags, ", "),
+		item.ID,
+	}
+}
+
+func (s *GoogleTransactionRepository) row(item *domain.Transaction) []interface{} {
+	var date interface{} = googleText(item.Date.Format(time.RFC3339Nano))
+	if s.formatted {
+		date = googleSerial(item.Date, s.location)
+	}
+	return []interface{}{
+		googleText(item.Account),
+		googleText(item.Party),
+		googleText(item.Direction),
+		item.Amount.String(),
+		googleText(item.Currency),
+		date,
+		item.Total.String(),
+		googleText(item.Raw),
+		googleText(item.Category),
+		googleText(strings.Join(item.Tags, ", ")),
+		googleText(item.ID),
+	}
+}
+
+func googleText(value string) string {
+	if value == "" {
+		return value
+	}
+	return "'" + value
+}
+
+func googleRowTransaction(row []interface{}, loc *time.Location) (domain.Transaction, bool) {
+	cell := func(i int) string {
+		if i < len(row) {
+			return fmt.Sprint(row[i])
+		}
+		return ""
+	}
+	number := func(i int) (decimal.Decimal, error) {
+		if i < len(row) {
+			if v, ok := row[i].(float64); ok {
+				return decimal.NewFromFloat(v), nil
+			}
+		}
+		return decimal.NewFromString(cell(i))
+	}
+	amount, err := number(3)
+	if err != nil {
+		return domain.Transaction{}, false
+	}
+	var date time.Time
+	if len(row) < 6 {
+		return domain.Transaction{}, false
+	}
+	if v, ok := row[5].(float64); ok {
+		date = googleSerialTime(v, loc)
+	} else if date, err = time.Parse(time.RFC3339Nano, cell(5)); err != nil {
+		return domain.Transaction{}, false
+	}
+	total, _ := number(6)
+	var tags []string
+	for _, v := range strings.Split(cell(9), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			tags = append(tags, v)
+		}
+	}
+	return domain.Transaction{
+		ID:        cell(10),
+		Account:   cell(0),
+		Party:     cell(1),
+		Direction: cell(2),
+		Amount:    amount,
+		Currency:  cell(4),
+		Date:      date,
+		Total:     total,
+		Raw:       cell(7),
+		Category:  cell(8),
+		Tags:      tags,
+	}, true
+}
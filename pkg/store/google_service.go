@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	GoogleAuthOAuth          = "oauth"
+	GoogleAuthServiceAccount = "service_account"
+)
+
+type GoogleServiceAccount struct {
+	config *jwt.Config
+}
+
+func NewGoogleServiceAccount(credentials []byte, scopes ...string) (*GoogleServiceAccount, error) {
+	config, err := google.JWTConfigFromJSON(credentials, scopes...)
+	if err != nil {
+		return nil, err
+	}
+	if config.Email == "" {
+		return nil, errors.New("service account: client_email not set")
+	}
+	return &GoogleServiceAccount{config: config}, nil
+}
+
+func (s *GoogleServiceAccount) Email() string {
+	return s.config.Email
+}
+
+func (s *GoogleServiceAccount) Client(ctx context.Context) *http.Client {
+	return s.config.Client(ctx)
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serviceAccountJSON(t *testing.T, tokenURI string) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "bot@project.iam.gserviceaccount.com",
+		"private_key_id": "kid",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      tokenURI,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func TestNewGoogleServiceAccount(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			assert.NoError(t, r.ParseForm())
+			assert.NotEmpty(t, r.PostForm.Get("assertion"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"sa","token_type":"Bearer","expires_in":3600}`))
+			return
+		}
+		auth = r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	account, err := NewGoogleServiceAccount(serviceAccountJSON(t, srv.URL+"/token"), "https://www.googleapis.com/auth/spreadsheets")
+	require.NoError(t, err)
+	assert.Equal(t, "bot@project.iam.gserviceaccount.com", account.Email())
+
+	resp, err := account.Client(context.Background()).Get(srv.URL + "/sheet")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, "Bearer sa", auth)
+
+	_, err = NewGoogleServiceAccount([]byte(`{"type":"authorized_user"}`))
+	assert.Error(t, err)
+}
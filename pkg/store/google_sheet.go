@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+const (
+	googleDefaultList     = "Transactions"
+	googleMonthListLayout = "2006-01"
+)
+
+var googleColumnFormats = map[string]*sheets.NumberFormat{
+	"Amount": {Type: "NUMBER", Pattern: "0.00"},
+	"Total":  {Type: "NUMBER", Pattern: "0.00"},
+	"Date":   {Type: "DATE_TIME", Pattern: "yyyy-mm-dd hh:mm:ss"},
+}
+
+type GoogleSpreadsheet struct {
+	srv *sheets.Service
+}
+
+func NewGoogleSpreadsheet(client *http.Client) (*GoogleSpreadsheet, error) {
+	return newGoogleSpreadsheet(option.WithHTTPClient(client))
+}
+
+func newGoogleSpreadsheet(opts ...option.ClientOption) (*GoogleSpreadsheet, error) {
+	srv, err := sheets.NewService(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &GoogleSpreadsheet{srv: srv}, nil
+}
+
+func GoogleDefaultLists() []string {
+	return []string{googleDefaultList}
+}
+
+func GoogleMonthlyList(date time.Time) string {
+	return date.Format(googleMonthListLayout)
+}
+
+func GoogleMonthlyLists(year int) []string {
+	var lists []string
+	for m := time.January; m <= time.December; m++ {
+		lists = append(lists, GoogleMonthlyList(time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)))
+	}
+	return lists
+}
+
+func (s *GoogleSpreadsheet) Create(ctx context.Context, title string, lists []string) (string, error) {
+	if len(lists) == 0 {
+		lists = GoogleDefaultLists()
+	}
+	spreadsheet := &sheets.Spreadsheet{Properties: &sheets.SpreadsheetProperties{Title: title}}
+	for _, v := range lists {
+		spreadsheet.Sheets = append(spreadsheet.Sheets, &sheets.Sheet{Properties: googleListProperties(v)})
+	}
+	resp, err := s.srv.Spreadsheets.Create(spreadsheet).Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	var requests []*sheets.Request
+	for _, v := range resp.Sheets {
+		if v.Properties != nil {
+			requests = append(requests, googleListFormatRequests(v.Properties.SheetId)...)
+		}
+	}
+	_, err = s.srv.Spreadsheets.BatchUpdate(resp.SpreadsheetId, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: requests,
+	}).Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	return resp.SpreadsheetId, nil
+}
+
+func (s *GoogleSpreadsheet) EnsureList(ctx context.Context, sheetID, list string) error {
+	resp, err := s.srv.Spreadsheets.Get(sheetID).Fields("sheets.properties").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	for _, v := range resp.Sheets {
+		if v.Properties != nil && v.Properties.Title == list {
+			return nil
+		}
+	}
+	added, err := s.srv.Spreadsheets.BatchUpdate(sheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{{AddSheet: &sheets.AddSheetRequest{Properties: googleListProperties(list)}}},
+	}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	if len(added.Replies) == 0 || added.Replies[0].AddSheet == nil || added.Replies[0].AddSheet.Properties == nil {
+		return fmt.Errorf("sheet/google: list %q not added", list)
+	}
+	_, err = s.srv.Spreadsheets.BatchUpdate(sheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: googleListFormatRequests(added.Replies[0].AddSheet.Properties.SheetId),
+	}).Context(ctx).Do()
+	return err
+}
+
+func googleListProperties(title string) *sheets.SheetProperties {
+	return &sheets.SheetProperties{
+		Title:          title,
+		GridProperties: &sheets.GridProperties{FrozenRowCount: 1},
+	}
+}
+
+func googleListFormatRequests(sheetID int64) []*sheets.Request {
+	header := &sheets.RowData{}
+	for _, v := range GoogleTransactionColumns {
+		value := v
+		header.Values = append(header.Values, &sheets.CellData{
+			UserEnteredValue:  &sheets.ExtendedValue{StringValue: &value},
+			UserEnteredFormat: &sheets.CellFormat{TextFormat: &sheets.TextFormat{Bold: true}},
+		})
+	}
+	requests := []*sheets.Request{{
+		UpdateCells: &sheets.UpdateCellsRequest{
+			Start:  &sheets.GridCoordinate{SheetId: sheetID, ForceSendFields: []string{"SheetId", "RowIndex", "ColumnIndex"}},
+			Rows:   []*sheets.RowData{header},
+			Fields: "userEnteredValue,userEnteredFormat.textFormat.bold",
+		},
+	}}
+	for i, v := range GoogleTransactionColumns {
+		format, ok := googleColumnFormats[v]
+		if !ok {
+			continue
+		}
+		requests = append(requests, &sheets.Request{
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range: &sheets.GridRange{
+					SheetId:          sheetID,
+					StartRowIndex:    1,
+					StartColumnIndex: int64(i),
+					EndColumnIndex:   int64(i + 1),
+					ForceSendFields:  []string{"SheetId", "StartColumnIndex"},
+				},
+				Cell:   &sheets.CellData{UserEnteredFormat: &sheets.CellFormat{NumberFormat: format}},
+				Fields: "userEnteredFormat.numberFormat",
+			},
+		})
+	}
+	return append(requests, &sheets.Request{
+		AutoResizeDimensions: &sheets.AutoResizeDimensionsRequest{
+			Dimensions: &sheets.DimensionRange{
+				SheetId:         sheetID,
+				Dimension:       "COLUMNS",
+				StartIndex:      0,
+				EndIndex:        int64(len(GoogleTransactionColumns)),
+				ForceSendFields: []string{"SheetId", "StartIndex"},
+			},
+		},
+	})
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+type fakeSpreadsheets struct {
+	mu      sync.Mutex
+	lists   []*sheets.SheetProperties
+	created *sheets.Spreadsheet
+	updates []*sheets.Request
+}
+
+func (f *fakeSpreadsheets) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reply := func(v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v4/spreadsheets":
+		f.created = &sheets.Spreadsheet{}
+		_ = json.NewDecoder(r.Body).Decode(f.created)
+		resp := &sheets.Spreadsheet{SpreadsheetId: "created"}
+		for i, v := range f.created.Sheets {
+			props := *v.Properties
+			props.SheetId = int64(i)
+			f.lists = append(f.lists, &props)
+			resp.Sheets = append(resp.Sheets, &sheets.Sheet{Properties: &props})
+		}
+		reply(resp)
+	case r.Method == http.MethodGet && r.URL.Path == "/v4/spreadsheets/created":
+		resp := &sheets.Spreadsheet{SpreadsheetId: "created"}
+		for _, v := range f.lists {
+			resp.Sheets = append(resp.Sheets, &sheets.Sheet{Properties: v})
+		}
+		reply(resp)
+	case r.Method == http.MethodPost && r.URL.Path == "/v4/spreadsheets/created:batchUpdate":
+		req := &sheets.BatchUpdateSpreadsheetRequest{}
+		_ = json.NewDecoder(r.Body).Decode(req)
+		resp := &sheets.BatchUpdateSpreadsheetResponse{}
+		for _, v := range req.Requests {
+			f.updates = append(f.updates, v)
+			if v.AddSheet != nil {
+				props := *v.AddSheet.Properties
+				props.SheetId = int64(100 + len(f.lists))
+				f.lists = append(f.lists, &props)
+				resp.Replies = append(resp.Replies, &sheets.Response{AddSheet: &sheets.AddSheetResponse{Properties: &props}})
+			}
+		}
+		reply(resp)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakeGoogleSpreadsheet(t *testing.T) (*fakeSpreadsheets, *GoogleSpreadsheet) {
+	fake := &fakeSpreadsheets{}
+	srv := httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(srv.Close)
+	spreadsheet, err := newGoogleSpreadsheet(option.WithEndpoint(srv.URL), option.WithHTTPClient(srv.Client()))
+	require.NoError(t, err)
+	return fake, spreadsheet
+}
+
+func TestGoogleSpreadsheet_Create(t *testing.T) {
+	fake, spreadsheet := newFakeGoogleSpreadsheet(t)
+
+	id, err := spreadsheet.Create(context.Background(), "Bank", GoogleMonthlyLists(2020))
+	require.NoError(t, err)
+	assert.Equal(t, "created", id)
+	assert.Equal(t, "Bank", fake.created.Properties.Title)
+	require.Len(t, fake.created.Sheets, 12)
+	assert.Equal(t, "2020-01", fake.created.Sheets[0].Properties.Title)
+	assert.Equal(t, "2020-12", fake.created.Sheets[11].Properties.Title)
+	assert.Equal(t, int64(1), fake.created.Sheets[0].Properties.GridProperties.FrozenRowCount)
+
+	var headers, formats int
+	for _, v := range fake.updates {
+		if v.UpdateCells != nil {
+			headers++
+			var header []string
+			for _, cell := range v.UpdateCells.Rows[0].Values {
+				header = append(header, *cell.UserEnteredValue.StringValue)
+				assert.True(t, cell.UserEnteredFormat.TextFormat.Bold)
+			}
+			assert.Equal(t, GoogleTransactionColumns, header)
+		}
+		if v.RepeatCell != nil {
+			formats++
+			assert.Equal(t, int64(1), v.RepeatCell.Range.StartRowIndex)
+		}
+	}
+	assert.Equal(t, 12, headers)
+	assert.Equal(t, 12*len(googleColumnFormats), formats)
+}
+
+func TestGoogleSpreadsheet_EnsureList(t *testing.T) {
+	fake, spreadsheet := newFakeGoogleSpreadsheet(t)
+
+	_, err := spreadsheet.Create(context.Background(), "Bank", nil)
+	require.NoError(t, err)
+	require.Len(t, fake.lists, 1)
+	assert.Equal(t, "Transactions", fake.lists[0].Title)
+
+	fake.updates = nil
+	require.NoError(t, spreadsheet.EnsureList(context.Background(), "created", "Transactions"))
+	assert.Empty(t, fake.updates)
+
+	list := GoogleMonthlyList(time.Date(2021, 1, 15, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, spreadsheet.EnsureList(context.Background(), "created", list))
+	require.Len(t, fake.lists, 2)
+	assert.Equal(t, "2021-01", fake.lists[1].Title)
+	assert.Equal(t, int64(1), fake.lists[1].GridProperties.FrozenRowCount)
+
+	var header bool
+	for _, v := range fake.updates {
+		if v.UpdateCells != nil {
+			header = v.UpdateCells.Start.SheetId == fake.lists[1].SheetId
+		}
+	}
+	assert.True(t, header)
+}
@@ -0,0 +1,372 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+)
+
+var fakeA1Rx = regexp.MustCompile(`^([A-Z])(\d*)(?::([A-Z])(\d*))?$`)
+
+type fakeSheet struct {
+	mu    sync.Mutex
+	title string
+	rows  [][]interface{}
+	tabs  []*fakeTab
+	input [][]interface{}
+}
+
+type fakeTab struct {
+	id    int
+	title string
+	rows  [][]interface{}
+}
+
+func newFakeSheetServer(t *testing.T, title string) (*fakeSheet, *httptest.Server) {
+	sheet := &fakeSheet{title: title}
+	srv := httptest.NewServer(http.HandlerFunc(sheet.handle))
+	t.Cleanup(srv.Close)
+	return sheet, srv
+}
+
+func newFakeGoogleTransactionRepository(t *testing.T, title string, opts ...GoogleRepositoryOption) (*fakeSheet, *GoogleTransactionRepository) {
+	sheet, srv := newFakeSheetServer(t, title)
+	repo, err := newGoogleTransactionRepository("sheet", title,
+		option.WithEndpoint(srv.URL), option.WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opt := range opts {
+		opt(repo)
+	}
+	return sheet, repo
+}
+
+func (f *fakeSheet) tab(title string) *[][]interface{} {
+	if title == "" || title == f.title {
+		return &f.rows
+	}
+	for _, v := range f.tabs {
+		if v.title == title {
+			return &v.rows
+		}
+	}
+	return nil
+}
+
+func (f *fakeSheet) tabByID(id int) *[][]interface{} {
+	if id == 7 {
+		return &f.rows
+	}
+	for _, v := range f.tabs {
+		if v.id == id {
+			return &v.rows
+		}
+	}
+	return nil
+}
+
+func (f *fakeSheet) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v4/spreadsheets/sheet")
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		sheets := []interface{}{
+			map[string]interface{}{"properties": map[string]interface{}{"sheetId": 3, "title": "Other"}},
+			map[string]interface{}{"properties": map[string]interface{}{"sheetId": 7, "title": f.title}},
+		}
+		for _, v := range f.tabs {
+			sheets = append(sheets, map[string]interface{}{"properties": map[string]interface{}{"sheetId": v.id, "title": v.title}})
+		}
+		f.reply(w, map[string]interface{}{"sheets": sheets})
+	case r.Method == http.MethodPost && path == ":batchUpdate":
+		var req struct {
+			Requests []struct {
+				DeleteDimension *struct {
+					Range struct {
+						SheetID    int `json:"sheetId"`
+						StartIndex int `json:"startIndex"`
+						EndIndex   int `json:"endIndex"`
+					} `json:"range"`
+				} `json:"deleteDimension"`
+				AddSheet *struct {
+					Properties struct {
+						Title string `json:"title"`
+					} `json:"properties"`
+				} `json:"addSheet"`
+			} `json:"requests"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var replies []interface{}
+		for _, v := range req.Requests {
+			switch {
+			case v.DeleteDimension != nil:
+				rng := v.DeleteDimension.Range
+				rows := f.tabByID(rng.SheetID)
+				if rows == nil || rng.EndIndex > len(*rows) {
+					http.Error(w, "bad range", http.StatusBadRequest)
+					return
+				}
+				*rows = append((*rows)[:rng.StartIndex], (*rows)[rng.EndIndex:]...)
+				replies = append(replies, map[string]interface{}{})
+			case v.AddSheet != nil:
+				tab := &fakeTab{id: 100 + len(f.tabs), title: v.AddSheet.Properties.Title, rows: [][]interface{}{{"Account", "Party"}}}
+				f.tabs = append(f.tabs, tab)
+				replies = append(replies, map[string]interface{}{
+					"addSheet": map[string]interface{}{"properties": map[string]interface{}{"sheetId": tab.id, "title": tab.title}},
+				})
+			default:
+				replies = append(replies, map[string]interface{}{})
+			}
+		}
+		f.reply(w, map[string]interface{}{"replies": replies})
+	case strings.HasPrefix(path, "/values/"):
+		rng := strings.TrimPrefix(path, "/values/")
+		isAppend := r.Method == http.MethodPost && strings.HasSuffix(rng, ":append")
+		rng = strings.TrimSuffix(rng, ":append")
+		title, ok := googleRefList(rng)
+		if !ok {
+			title = ""
+		}
+		rows := f.tab(title)
+		if rows == nil {
+			http.Error(w, "bad range "+rng, http.StatusBadRequest)
+			return
+		}
+		prefix := googleA1Range(title, "")
+		if r.Method != http.MethodGet && r.URL.Query().Get("valueInputOption") != googleValueInput {
+			http.Error(w, "bad value input option", http.StatusBadRequest)
+			return
+		}
+		if isAppend {
+			var req struct {
+				Values [][]interface{} `json:"values"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			f.input = req.Values
+			*rows = append(*rows, fakeUserEntered(req.Values)...)
+			n := len(*rows)
+			f.reply(w, map[string]interface{}{
+				"updates": map[string]interface{}{"updatedRange": fmt.Sprintf("%sA%d:K%d", prefix, n, n)},
+			})
+			return
+		}
+		c1, r1, c2, r2 := parseFakeA1(strings.TrimPrefix(rng, prefix))
+		switch r.Method {
+		case http.MethodGet:
+			var values [][]interface{}
+			for i, row := range *rows {
+				if (r1 > 0 && i+1 < r1) || (r2 > 0 && i+1 > r2) {
+					continue
+				}
+				var cells []interface{}
+				for c := c1; c <= c2 && c < len(row); c++ {
+					cells = append(cells, row[c])
+				}
+				for len(cells) > 0 && cells[len(cells)-1] == "" {
+					cells = cells[:len(cells)-1]
+				}
+				values = append(values, cells)
+			}
+			f.reply(w, map[string]interface{}{"range": rng, "values": values})
+		case http.MethodPut:
+			var req struct {
+				Values [][]interface{} `json:"values"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if r1 < 1 || r1 > len(*rows) || len(req.Values) != 1 {
+				http.Error(w, "bad range "+rng, http.StatusBadRequest)
+				return
+			}
+			f.input = req.Values
+			(*rows)[r1-1] = fakeUserEntered(req.Values)[0]
+			f.reply(w, map[string]interface{}{"updatedRange": rng})
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func fakeUserEntered(values [][]interface{}) [][]interface{} {
+	result := make([][]interface{}, 0, len(values))
+	for _, row := range values {
+		cells := make([]interface{}, 0, len(row))
+		for _, v := range row {
+			if text, ok := v.(string); ok {
+				if strings.HasPrefix(text, "'") {
+					v = text[1:]
+				} else if number, err := strconv.ParseFloat(text, 64); err == nil {
+					v = number
+				}
+			}
+			cells = append(cells, v)
+		}
+		result = append(result, cells)
+	}
+	return result
+}
+
+func (f *fakeSheet) reply(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func parseFakeA1(cells string) (c1, r1, c2, r2 int) {
+	match := fakeA1Rx.FindStringSubmatch(cells)
+	if match == nil {
+		return 0, 0, -1, 0
+	}
+	c1 = int(match[1][0] - 'A')
+	r1, _ = strconv.Atoi(match[2])
+	c2, r2 = c1, r1
+	if match[3] != "" {
+		c2 = int(match[3][0] - 'A')
+		r2, _ = strconv.Atoi(match[4])
+	}
+	return c1, r1, c2, r2
+}
+
+func TestGoogleTransactionRepository_findRow(t *testing.T) {
+	sheet, repo := newFakeGoogleTransactionRepository(t, "Bank's list")
+	sheet.rows = [][]interface{}{
+		{"Account", "Party", "Direction", "Amount"},
+		{"1", "A", "", "1", "AED", "2020-11-01T00:00:00Z", "0", "", "", "", "id1"},
+		{"1", "B", "", "2", "AED", "2020-11-01T00:00:00Z", "0", "", "", "", "id2"},
+	}
+
+	ctx := context.Background()
+	ref := func(id, rng string) *domain.Transaction {
+		return &domain.Transaction{ID: id, Refs: map[string]string{googleRefKey: rng}}
+	}
+
+	list, row, err := repo.findRow(ctx, ref("id2", "'Bank''s list'!A3:K3"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Bank's list", list)
+	assert.Equal(t, 3, row)
+
+	_, row, err = repo.findRow(ctx, ref("id2", "'Bank''s list'!A7:K7"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, row, "stale ref falls back to id column")
+
+	_, _, err = repo.findRow(ctx, ref("id3", ""))
+	assert.Equal(t, ErrTransactionNotFound, err)
+}
+
+func TestGoogleTransactionRecord(t *testing.T) {
+	item := domain.Transaction{
+		ID: "id1", Account: "5098", Party: "CAFE", Direction: "debit", Amount: decimal.RequireFromString("-12.5"), Currency: "AED",
+		Date: time.Date(2020, 11, 1, 10, 0, 0, 0, time.UTC), Total: decimal.RequireFromString("100"), Raw: "cafe", Category: "Food", Tags: []string{"a", "b"},
+	}
+	record := GoogleTransactionRecord(&item)
+	assert.Len(t, record, len(GoogleTransactionColumns))
+	assert.Equal(t, []string{"5098", "CAFE", "debit", "-12.5", "AED", "2020-11-01T10:00:00Z", "100", "cafe", "Food", "a, b", "id1"}, record)
+
+	row := make([]interface{}, len(record))
+	for i, v := range record {
+		row[i] = v
+	}
+	got, ok := googleRowTransaction(row, time.UTC)
+	if assert.True(t, ok) {
+		assert.Equal(t, item.ID, got.ID)
+		assert.Equal(t, item.Tags, got.Tags)
+		assert.True(t, item.Amount.Equal(got.Amount))
+		assert.True(t, item.Date.Equal(got.Date))
+	}
+}
+
+func TestGoogleTransactionRepository_Store_values(t *testing.T) {
+	dubai := time.FixedZone("Dubai", 4*60*60)
+	sheet, repo := newFakeGoogleTransactionRepository(t, "Transactions", WithGoogleLocation(dubai), WithGoogleFormattedLists())
+	item := domain.Transaction{
+		ID: "1e5", Account: "0098", Party: "=CAFE", Direction: "debit", Amount: decimal.RequireFromString("-12345678901234.57"), Currency: "AED",
+		Date: time.Date(2020, 11, 1, 20, 30, 0, 0, time.UTC), Total: decimal.RequireFromString("100"),
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, repo.Store(ctx, &item))
+	if assert.Len(t, sheet.input, 1) {
+		row := sheet.input[0]
+		assert.Equal(t, "-12345678901234.57", row[3], "decimal string keeps precision")
+		assert.Equal(t, "100", row[6])
+		assert.Equal(t, "'=CAFE", row[1], "text is never parsed as a formula")
+		assert.InDelta(t, 44137.0+30.0/(24*60), row[5], 1e-9, "serial date in the user's zone")
+	}
+	if assert.Len(t, sheet.rows, 1) {
+		assert.Equal(t, "0098", sheet.rows[0][0])
+		assert.Equal(t, "1e5", sheet.rows[0][10])
+	}
+
+	got, err := repo.Get(ctx, 1, "1e5")
+	if assert.NoError(t, err) {
+		assert.True(t, item.Amount.Equal(got.Amount))
+		assert.True(t, item.Total.Equal(got.Total))
+		assert.True(t, item.Date.Equal(got.Date))
+		assert.Equal(t, "=CAFE", got.Party)
+	}
+}
+
+func TestGoogleTransactionRepository_Store_unformatted(t *testing.T) {
+	sheet, repo := newFakeGoogleTransactionRepository(t, "Transactions")
+	item := domain.Transaction{ID: "id1", Amount: decimal.RequireFromString("-5"), Currency: "AED", Date: time.Date(2020, 11, 1, 20, 30, 0, 0, time.UTC)}
+
+	ctx := context.Background()
+	assert.NoError(t, repo.Store(ctx, &item))
+	if assert.Len(t, sheet.rows, 1) {
+		assert.Equal(t, "2020-11-01T20:30:00Z", sheet.rows[0][5], "sheets without date format get readable dates")
+	}
+
+	got, err := repo.Get(ctx, 1, "id1")
+	if assert.NoError(t, err) {
+		assert.True(t, item.Date.Equal(got.Date))
+	}
+}
+
+func TestGoogleTransactionRepository_monthly(t *testing.T) {
+	sheet, repo := newFakeGoogleTransactionRepository(t, "Transactions", WithGoogleMonthlyLists())
+	ctx := context.Background()
+	late := domain.Transaction{ID: "late", Amount: decimal.RequireFromString("-5"), Currency: "AED", Date: time.Date(2020, 10, 31, 10, 0, 0, 0, time.UTC)}
+	item := domain.Transaction{ID: "now", Amount: decimal.RequireFromString("-7"), Currency: "AED", Date: time.Date(2020, 11, 2, 10, 0, 0, 0, time.UTC)}
+	assert.NoError(t, repo.Store(ctx, &item))
+	assert.NoError(t, repo.Store(ctx, &late))
+
+	assert.Equal(t, "'2020-10'!A2:K2", late.Refs[googleRefKey])
+	assert.Equal(t, "'2020-11'!A2:K2", item.Refs[googleRefKey])
+	assert.Len(t, sheet.rows, 0)
+
+	items, err := repo.List(ctx, domain.TransactionFilter{From: time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC)})
+	assert.NoError(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "now", items[0].ID)
+	}
+
+	late.Category = "Food"
+	assert.NoError(t, repo.Update(ctx, &late))
+	got, err := repo.Get(ctx, 1, "late")
+	assert.NoError(t, err)
+	assert.Equal(t, "Food", got.Category)
+
+	late.Date = item.Date
+	assert.NoError(t, repo.Update(ctx, &late), "moves the row to the new month")
+	assert.Equal(t, "'2020-11'!A3:K3", late.Refs[googleRefKey])
+	assert.Len(t, *sheet.tab("2020-10"), 1)
+
+	assert.NoError(t, repo.Delete(ctx, &item))
+	assert.Len(t, *sheet.tab("2020-11"), 2)
+	_, err = repo.Get(ctx, 1, "now")
+	assert.Equal(t, ErrTransactionNotFound, err)
+}
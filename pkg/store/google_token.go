@@ -0,0 +1,101 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+var ErrTokenRevoked = errors.New("store/google: token revoked")
+
+type TokenOption func(s *persistingTokenSource)
+
+func WithTokenSave(fn func(token []byte) error) TokenOption {
+	return func(s *persistingTokenSource) {
+		s.save = fn
+	}
+}
+
+func WithTokenRevoked(fn func(err error)) TokenOption {
+	return func(s *persistingTokenSource) {
+		s.revoked = fn
+	}
+}
+
+type persistingTokenSource struct {
+	mu      sync.Mutex
+	base    oauth2.TokenSource
+	current *oauth2.Token
+	save    func(token []byte) error
+	revoked func(err error)
+	once    sync.Once
+}
+
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := s.base.Token()
+	if err != nil {
+		if !isTokenRevoked(err) {
+			return nil, err
+		}
+		err = fmt.Errorf("%w: %v", ErrTokenRevoked, err)
+		if s.revoked != nil {
+			s.once.Do(func() {
+				s.revoked(err)
+			})
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !tokenChanged(s.current, tok) {
+		return tok, nil
+	}
+	s.current = tok
+	if s.save != nil {
+		data, err := json.Marshal(tok)
+		if err == nil {
+			err = s.save(data)
+		}
+		if err != nil {
+			log.Println("save google token: ", err)
+		}
+	}
+	return tok, nil
+}
+
+func tokenChanged(current, tok *oauth2.Token) bool {
+	return current == nil ||
+		current.AccessToken != tok.AccessToken ||
+		current.RefreshToken != tok.RefreshToken ||
+		!current.Expiry.Equal(tok.Expiry)
+}
+
+func SameGoogleGrant(a, b []byte) bool {
+	tokA, tokB := &oauth2.Token{}, &oauth2.Token{}
+	if json.Unmarshal(a, tokA) != nil || json.Unmarshal(b, tokB) != nil {
+		return false
+	}
+	if tokA.RefreshToken != "" || tokB.RefreshToken != "" {
+		return tokA.RefreshToken == tokB.RefreshToken
+	}
+	return tokA.AccessToken == tokB.AccessToken
+}
+
+func isTokenRevoked(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) {
+		return false
+	}
+	body := struct {
+		Error string `json:"error"`
+	}{}
+	if json.Unmarshal(retrieveErr.Body, &body) != nil {
+		return false
+	}
+	return body.Error == "invalid_grant" || body.Error == "unauthorized_client"
+}
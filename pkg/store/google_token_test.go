@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+type fakeTokenServer struct {
+	mu       sync.Mutex
+	response string
+	status   int
+	refresh  int
+}
+
+func (f *fakeTokenServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path != "/token" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	f.refresh++
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(f.status)
+	_, _ = w.Write([]byte(f.response))
+}
+
+func newFakeGoogleClient(t *testing.T) (*fakeTokenServer, *httptest.Server, *GoogleClient) {
+	fake := &fakeTokenServer{status: http.StatusOK}
+	srv := httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(srv.Close)
+	return fake, srv, NewGoogleClient(&oauth2.Config{
+		ClientID: "id",
+		Endpoint: oauth2.Endpoint{TokenURL: srv.URL + "/token", AuthStyle: oauth2.AuthStyleInParams},
+	})
+}
+
+func expiredToken(t *testing.T) []byte {
+	data, err := json.Marshal(&oauth2.Token{AccessToken: "old", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+	return data
+}
+
+func TestGoogleClient_Get_SavesRefreshedToken(t *testing.T) {
+	fake, srv, client := newFakeGoogleClient(t)
+	fake.response = `{"access_token":"new","refresh_token":"rotated","token_type":"Bearer","expires_in":3600}`
+
+	var saved [][]byte
+	httpClient, err := client.Get(context.Background(), expiredToken(t), WithTokenSave(func(token []byte) error {
+		saved = append(saved, token)
+		return nil
+	}))
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		resp, err := httpClient.Get(srv.URL + "/api")
+		if assert.NoError(t, err) {
+			_ = resp.Body.Close()
+		}
+	}
+	assert.Equal(t, 1, fake.refresh, "refreshed token is reused")
+	if assert.Len(t, saved, 1) {
+		tok := &oauth2.Token{}
+		require.NoError(t, json.Unmarshal(saved[0], tok))
+		assert.Equal(t, "new", tok.AccessToken)
+		assert.Equal(t, "rotated", tok.RefreshToken)
+	}
+}
+
+func TestGoogleClient_Get_RevokedToken(t *testing.T) {
+	fake, srv, client := newFakeGoogleClient(t)
+	fake.status = http.StatusBadRequest
+	fake.response = `{"error":"invalid_grant","error_description":"Token has been expired or revoked."}`
+
+	revoked := 0
+	httpClient, err := client.Get(context.Background(), expiredToken(t), WithTokenRevoked(func(err error) {
+		assert.True(t, errors.Is(err, ErrTokenRevoked))
+		revoked++
+	}))
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = httpClient.Get(srv.URL + "/api")
+		assert.True(t, errors.Is(err, ErrTokenRevoked), "%v", err)
+	}
+	assert.Equal(t, 1, revoked)
+}
+
+func TestGoogleClient_Get_TemporaryError(t *testing.T) {
+	fake, srv, client := newFakeGoogleClient(t)
+	fake.status = http.StatusInternalServerError
+	fake.response = `{"error":"backend_error"}`
+
+	httpClient, err := client.Get(context.Background(), expiredToken(t), WithTokenRevoked(func(err error) {
+		t.Error("unexpected revoke")
+	}))
+	require.NoError(t, err)
+
+	_, err = httpClient.Get(srv.URL + "/api")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrTokenRevoked))
+}
+
+func TestSameGoogleGrant(t *testing.T) {
+	assert.True(t, SameGoogleGrant([]byte(`{"access_token":"a","refresh_token":"r"}`), []byte(`{"access_token":"b","refresh_token":"r"}`)))
+	assert.False(t, SameGoogleGrant([]byte(`{"access_token":"a","refresh_token":"r"}`), []byte(`{"access_token":"a","refresh_token":"r2"}`)))
+	assert.True(t, SameGoogleGrant([]byte(`{"access_token":"a"}`), []byte(`{"access_token":"a"}`)))
+	assert.False(t, SameGoogleGrant([]byte(`{"access_token":"a"}`), []byte(`{"access_token":"b"}`)))
+	assert.False(t, SameGoogleGrant([]byte(`{"access_token":"a"}`), nil))
+}
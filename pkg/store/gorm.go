@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ftomza/go-bank-bot/domain"
 	"gorm.io/gorm"
@@ -15,36 +17,82 @@ type TrxPatterns []domain.TrxPattern
 type User struct {
 	gorm.Model
 
-	BotUserID   int    `gorm:"unique"`
-	TokSheet    []byte `gorm:"index"`
+	BotUserID   int `gorm:"unique"`
+	TokSheet    []byte
+	AuthMode    string
 	SheetID     string `gorm:"index"`
 	ListName    string
+	MonthlyList bool
+	Formatted   bool
 	TrxPatterns TrxPatterns
+
+	AllowDuplicates bool
+	NumberDecimal   string
+	NumberGroup     string
+	Timezone        string
+	BaseCurrency    string
+
+	DigestSchedule string
+	DigestTime     string
+	LastDigestAt   time.Time
 }
 
 type DomainUser domain.User
 
 func (u DomainUser) ToUser() User {
+	var numberFormat domain.NumberFormat
+	if u.NumberFormat != nil {
+		numberFormat = *u.NumberFormat
+	}
 	return User{
 		Model: gorm.Model{
 			ID: u.ID,
 		},
 		BotUserID:   u.BotUserID,
 		TokSheet:    u.TokSheet,
+		AuthMode:    u.AuthMode,
 		SheetID:     u.SheetID,
 		ListName:    u.ListName,
+		MonthlyList: u.MonthlyList,
+		Formatted:   u.Formatted,
 		TrxPatterns: u.TrxPatterns,
+
+		AllowDuplicates: u.AllowDuplicates,
+		NumberDecimal:   numberFormat.Decimal,
+		NumberGroup:     numberFormat.Group,
+		Timezone:        u.Timezone,
+		BaseCurrency:    u.BaseCurrency,
+
+		DigestSchedule: u.DigestSchedule,
+		DigestTime:     u.DigestTime,
+		LastDigestAt:   u.LastDigestAt,
 	}
 }
 
 func (u User) ToAPIMessage() domain.User {
+	var numberFormat *domain.NumberFormat
+	if u.NumberDecimal != "" || u.NumberGroup != "" {
+		numberFormat = &domain.NumberFormat{Decimal: u.NumberDecimal, Group: u.NumberGroup}
+	}
 	return domain.User{
 		ID:          u.ID,
 		BotUserID:   u.BotUserID,
 		TokSheet:    u.TokSheet,
+		AuthMode:    u.AuthMode,
 		SheetID:     u.SheetID,
 		ListName:    u.ListName,
+		MonthlyList: u.MonthlyList,
+		Formatted:   u.Formatted,
 		TrxPatterns: u.TrxPatterns,
+
+		AllowDuplicates: u.AllowDuplicates,
+		NumberFormat:    numberFormat,
+		Timezone:        u.Timezone,
+		BaseCurrency:    u.BaseCurrency,
+
+		DigestSchedule: u.DigestSchedule,
+		DigestTime:     u.DigestTime,
+		LastDigestAt:   u.LastDigestAt,
 	}
 }
 
@@ -69,12 +117,130 @@ func (TrxPatterns) GormDataType() string {
 	return "string"
 }
 
+type Cipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(data []byte) ([]byte, error)
+	Encrypted(data []byte) bool
+}
+
+type GormUserOption func(g *gormUserRepository)
+
+func WithCipher(cipher Cipher) GormUserOption {
+	return func(g *gormUserRepository) {
+		g.cipher = cipher
+	}
+}
+
 type gormUserRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	cipher Cipher
+}
+
+func (g *gormUserRepository) Migration(ctx context.Context) error {
+	if err := g.db.AutoMigrate(&User{}); err != nil {
+		return err
+	}
+	if g.db.Migrator().HasIndex(&User{}, "idx_users_tok_sheet") {
+		if err := g.db.Migrator().DropIndex(&User{}, "idx_users_tok_sheet"); err != nil {
+			return err
+		}
+	}
+	if err := g.migrateTrxPatterns(); err != nil {
+		return err
+	}
+	if g.cipher == nil {
+		return nil
+	}
+	_, err := g.encryptTokens(ctx, false)
+	return err
+}
+
+func (g *gormUserRepository) encryptTokens(ctx context.Context, rotate bool) (int, error) {
+	var users []User
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Unscoped().Select("id", "tok_sheet").Where("tok_sheet IS NOT NULL").Find(&users).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, v := range users {
+		tok := v.TokSheet
+		if len(tok) == 0 || (!rotate && g.cipher.Encrypted(tok)) {
+			continue
+		}
+		if g.cipher.Encrypted(tok) {
+			if tok, err = g.cipher.Decrypt(tok); err != nil {
+				return count, fmt.Errorf("store/gorm: decrypt token of user %d: %w", v.ID, err)
+			}
+		}
+		if tok, err = g.cipher.Encrypt(tok); err != nil {
+			return count, err
+		}
+		err = g.wrapper(ctx, func(db *gorm.DB) error {
+			return db.Unscoped().Where("id = ?", v.ID).Update("tok_sheet", tok).Error
+		})
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (g *gormUserRepository) toUser(user domain.User) (User, error) {
+	item := DomainUser(user).ToUser()
+	if g.cipher == nil || len(item.TokSheet) == 0 {
+		return item, nil
+	}
+	var err error
+	item.TokSheet, err = g.cipher.Encrypt(item.TokSheet)
+	return item, err
+}
+
+func (g *gormUserRepository) toAPIMessage(item User) (domain.User, error) {
+	user := item.ToAPIMessage()
+	if g.cipher == nil || !g.cipher.Encrypted(user.TokSheet) {
+		return user, nil
+	}
+	var err error
+	if user.TokSheet, err = g.cipher.Decrypt(user.TokSheet); err != nil {
+		return user, fmt.Errorf("store/gorm: decrypt token of user %d: %w", item.ID, err)
+	}
+	return user, nil
+}
+
+func (g *gormUserRepository) migrateTrxPatterns() error {
+	var users []User
+	if err := g.db.Model(&User{}).Find(&users).Error; err != nil {
+		return err
+	}
+	for _, v := range users {
+		patterns, changed := migrateTrxPatterns(v.TrxPatterns)
+		if !changed {
+			continue
+		}
+		if err := g.db.Model(&User{}).Where("id = ?", v.ID).Update("trx_patterns", patterns).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (g *gormUserRepository) Migration(_ context.Context) error {
-	return g.db.AutoMigrate(&User{})
+func migrateTrxPatterns(patterns TrxPatterns) (TrxPatterns, bool) {
+	changed := false
+	result := make(TrxPatterns, 0, len(patterns))
+	for i, v := range patterns {
+		if v.Name == "" {
+			v.Name = v.Preset
+			if v.Name == "" {
+				v.Name = fmt.Sprintf("Pattern %d", i+1)
+			}
+			changed = true
+		}
+		result = append(result, v)
+	}
+	return result, changed
 }
 
 func (g *gormUserRepository) Get(ctx context.Context, id uint) (domain.User, error) {
@@ -82,7 +248,10 @@ func (g *gormUserRepository) Get(ctx context.Context, id uint) (domain.User, err
 	err := g.wrapper(ctx, func(db *gorm.DB) error {
 		return db.Where(&User{Model: gorm.Model{ID: id}}).Take(&item).Error
 	})
-	return item.ToAPIMessage(), err
+	if err != nil {
+		return item.ToAPIMessage(), err
+	}
+	return g.toAPIMessage(item)
 }
 
 func (g *gormUserRepository) GetByBotUserID(ctx context.Context, uid int) (domain.User, error) {
@@ -90,21 +259,75 @@ func (g *gormUserRepository) GetByBotUserID(ctx context.Context, uid int) (domai
 	err := g.wrapper(ctx, func(db *gorm.DB) error {
 		return db.Where(&User{BotUserID: uid}).Take(&item).Error
 	})
-	return item.ToAPIMessage(), err
+	if err != nil {
+		return item.ToAPIMessage(), err
+	}
+	return g.toAPIMessage(item)
+}
+
+func (g *gormUserRepository) List(ctx context.Context) ([]domain.User, error) {
+	var items []User
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Order("id").Find(&items).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result []domain.User
+	for _, v := range items {
+		user, err := g.toAPIMessage(v)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, user)
+	}
+	return result, nil
 }
 
 func (g *gormUserRepository) Store(ctx context.Context, user *domain.User) error {
+	item, err := g.toUser(*user)
+	if err != nil {
+		return err
+	}
 	return g.wrapper(ctx, func(db *gorm.DB) error {
-		item := DomainUser(*user).ToUser()
 		return db.Create(&item).Error
 	})
 }
 
 func (g *gormUserRepository) Update(ctx context.Context, user *domain.User) error {
+	item, err := g.toUser(*user)
+	if err != nil {
+		return err
+	}
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			return tx.Take(&User{}, user.ID).
+				Select("*").Omit("CreatedAt", "DeletedAt").
+				Updates(&item).Error
+		})
+	})
+}
+
+func (g *gormUserRepository) UpdateFields(ctx context.Context, user *domain.User, fields ...string) error {
+	if len(fields) == 0 {
+		return errors.New("store/gorm: user fields not set")
+	}
+	var columns []string
+	for _, v := range fields {
+		if v == "NumberFormat" {
+			columns = append(columns, "NumberDecimal", "NumberGroup")
+			continue
+		}
+		columns = append(columns, v)
+	}
+	item, err := g.toUser(*user)
+	if err != nil {
+		return err
+	}
 	return g.wrapper(ctx, func(db *gorm.DB) error {
 		return db.Transaction(func(tx *gorm.DB) error {
-			item := DomainUser(*user).ToUser()
 			return tx.Take(&User{}, user.ID).
+				Select(columns).
 				Updates(&item).Error
 		})
 	})
@@ -115,12 +338,22 @@ func (g *gormUserRepository) Delete(ctx context.Context, user *domain.User) erro
 		return db.Delete(&User{}, user.ID).Error
 	})
 }
+
 func (g *gormUserRepository) wrapper(ctx context.Context, fn func(db *gorm.DB) error) error {
 	return fn(g.db.WithContext(ctx).Model(&User{}))
 }
 
-func NewGormUserRepository(db *gorm.DB) domain.UserRepository {
-	return &gormUserRepository{
+func NewGormUserRepository(db *gorm.DB, opts ...GormUserOption) domain.UserRepository {
+	g := &gormUserRepository{
 		db: db,
 	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+func RotateGormUserTokens(ctx context.Context, db *gorm.DB, cipher Cipher) (int, error) {
+	g := &gormUserRepository{db: db, cipher: cipher}
+	return g.encryptTokens(ctx, true)
 }
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"gorm.io/gorm"
+)
+
+type Balance struct {
+	gorm.Model
+
+	UserID        uint   `gorm:"index"`
+	Account       string `gorm:"index"`
+	Currency      string
+	Amount        decimal.Decimal `gorm:"type:varchar(64)"`
+	Total         decimal.Decimal `gorm:"type:varchar(64)"`
+	Date          time.Time
+	TransactionID string `gorm:"index"`
+}
+
+type DomainBalance domain.Balance
+
+func (b DomainBalance) ToBalance() Balance {
+	return Balance{
+		Model: gorm.Model{
+			ID: b.ID,
+		},
+		UserID:        b.UserID,
+		Account:       b.Account,
+		Currency:      b.Currency,
+		Amount:        b.Amount,
+		Total:         b.Total,
+		Date:          b.Date.UTC(),
+		TransactionID: b.TransactionID,
+	}
+}
+
+func (b Balance) ToAPIMessage() domain.Balance {
+	return domain.Balance{
+		ID:            b.ID,
+		UserID:        b.UserID,
+		Account:       b.Account,
+		Currency:      b.Currency,
+		Amount:        b.Amount,
+		Total:         b.Total,
+		Date:          b.Date,
+		TransactionID: b.TransactionID,
+	}
+}
+
+type gormBalanceRepository struct {
+	db *gorm.DB
+}
+
+func (g *gormBalanceRepository) Migration(ctx context.Context) error {
+	if err := g.db.AutoMigrate(&Balance{}); err != nil {
+		return err
+	}
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		var rows []Balance
+		if err := db.Unscoped().Select("id", "date").Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, v := range rows {
+			if _, offset := v.Date.Zone(); offset == 0 {
+				continue
+			}
+			if err := db.Unscoped().Where("id = ?", v.ID).UpdateColumn("date", v.Date.UTC()).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (g *gormBalanceRepository) Last(ctx context.Context, userID uint, account string) (domain.Balance, error) {
+	item := Balance{}
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Where("user_id = ? AND account = ?", userID, account).Order("date desc, id desc").Take(&item).Error
+	})
+	return item.ToAPIMessage(), err
+}
+
+func (g *gormBalanceRepository) ListLatest(ctx context.Context, userID uint) ([]domain.Balance, error) {
+	var items []Balance
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		dates := g.db.WithContext(ctx).Model(&Balance{}).
+			Select("account, MAX(date) AS date").
+			Where("user_id = ?", userID).
+			Group("account")
+		ids := g.db.WithContext(ctx).Model(&Balance{}).
+			Select("MAX(balances.id)").
+			Joins("JOIN (?) latest ON latest.account = balances.account AND latest.date = balances.date", dates).
+			Where("balances.user_id = ?", userID).
+			Group("balances.account")
+		return db.Where("id IN (?)", ids).Order("account").Find(&items).Error
+	})
+	var result []domain.Balance
+	for _, v := range items {
+		result = append(result, v.ToAPIMessage())
+	}
+	return result, err
+}
+
+func (g *gormBalanceRepository) Store(ctx context.Context, balance *domain.Balance) error {
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		item := DomainBalance(*balance).ToBalance()
+		if err := db.Create(&item).Error; err != nil {
+			return err
+		}
+		balance.ID = item.ID
+		return nil
+	})
+}
+
+func (g *gormBalanceRepository) DeleteByTransaction(ctx context.Context, userID uint, transactionID string) error {
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Where(&Balance{UserID: userID, TransactionID: transactionID}).Delete(&Balance{}).Error
+	})
+}
+
+func (g *gormBalanceRepository) wrapper(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return fn(g.db.WithContext(ctx).Model(&Balance{}))
+}
+
+func NewGormBalanceRepository(db *gorm.DB) domain.BalanceRepository {
+	return &gormBalanceRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type GormBalanceRepositoryTestSuite struct {
+	suite.Suite
+	Ctx  context.Context
+	DB   *gorm.DB
+	Repo domain.BalanceRepository
+}
+
+func (suite *GormBalanceRepositoryTestSuite) SetupTest() {
+	var (
+		err error
+	)
+
+	suite.DB, err = gorm.Open(sqlite.Open("file:balance?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	suite.NoError(err)
+
+	suite.DB = suite.DB.Debug()
+
+	suite.Repo = NewGormBalanceRepository(suite.DB)
+
+	suite.Ctx = context.Background()
+
+	suite.NoError(suite.Repo.Migration(suite.Ctx))
+}
+
+func Test_GormBalanceRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(GormBalanceRepositoryTestSuite))
+}
+
+func (suite *GormBalanceRepositoryTestSuite) Test_GormBalanceRepository() {
+	date := func(day int) time.Time {
+		return time.Date(2020, 11, day, 10, 0, 0, 0, time.UTC)
+	}
+	items := []domain.Balance{
+		{UserID: 1, Account: "5098", Currency: "AED", Amount: decimal.NewFromInt(-20), Total: decimal.NewFromInt(980), Date: date(2), TransactionID: "t2"},
+		{UserID: 1, Account: "5098", Currency: "AED", Amount: decimal.NewFromInt(-10), Total: decimal.NewFromInt(1000), Date: date(1), TransactionID: "t1"},
+		{UserID: 1, Account: "1111", Currency: "USD", Amount: decimal.NewFromInt(100), Total: decimal.NewFromInt(300), Date: date(1), TransactionID: "t3"},
+		{UserID: 2, Account: "5098", Currency: "AED", Amount: decimal.NewFromInt(-5), Total: decimal.NewFromInt(5), Date: date(3), TransactionID: "t4"},
+	}
+
+	suite.Run("store", func() {
+		for i := range items {
+			suite.NoError(suite.Repo.Store(suite.Ctx, &items[i]))
+			suite.NotZero(items[i].ID)
+		}
+	})
+
+	suite.Run("last", func() {
+		got, err := suite.Repo.Last(suite.Ctx, 1, "5098")
+		if suite.NoError(err) {
+			suite.Equal("t2", got.TransactionID)
+			suite.True(decimal.NewFromInt(980).Equal(got.Total))
+		}
+		_, err = suite.Repo.Last(suite.Ctx, 1, "0000")
+		suite.True(errors.Is(err, gorm.ErrRecordNotFound))
+	})
+
+	suite.Run("list latest", func() {
+		got, err := suite.Repo.ListLatest(suite.Ctx, 1)
+		if suite.NoError(err) && suite.Len(got, 2) {
+			suite.Equal("1111", got[0].Account)
+			suite.Equal("5098", got[1].Account)
+			suite.Equal("t2", got[1].TransactionID)
+		}
+	})
+
+	suite.Run("mixed offsets", func() {
+		loc := time.FixedZone("+04", 4*60*60)
+		early := domain.Balance{UserID: 3, Account: "7777", Currency: "AED", Total: decimal.NewFromInt(100),
+			Date: time.Date(2020, 11, 2, 1, 0, 0, 0, loc), TransactionID: "t5"}
+		late := domain.Balance{UserID: 3, Account: "7777", Currency: "AED", Total: decimal.NewFromInt(90),
+			Date: time.Date(2020, 11, 1, 22, 0, 0, 0, time.UTC), TransactionID: "t6"}
+		suite.NoError(suite.Repo.Store(suite.Ctx, &late))
+		suite.NoError(suite.Repo.Store(suite.Ctx, &early))
+
+		got, err := suite.Repo.Last(suite.Ctx, 3, "7777")
+		if suite.NoError(err) {
+			suite.Equal("t6", got.TransactionID)
+		}
+		items, err := suite.Repo.ListLatest(suite.Ctx, 3)
+		if suite.NoError(err) && suite.Len(items, 1) {
+			suite.Equal("t6", items[0].TransactionID)
+		}
+	})
+
+	suite.Run("delete by transaction", func() {
+		suite.NoError(suite.Repo.DeleteByTransaction(suite.Ctx, 2, "t2"))
+		got, err := suite.Repo.Last(suite.Ctx, 1, "5098")
+		if suite.NoError(err) {
+			suite.Equal("t2", got.TransactionID, "other user")
+		}
+
+		suite.NoError(suite.Repo.DeleteByTransaction(suite.Ctx, 1, "t2"))
+		got, err = suite.Repo.Last(suite.Ctx, 1, "5098")
+		if suite.NoError(err) {
+			suite.Equal("t1", got.TransactionID)
+		}
+	})
+}
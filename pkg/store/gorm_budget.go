@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"gorm.io/gorm"
+)
+
+var ErrBudgetNotFound = errors.New("store: budget not found")
+
+type Budget struct {
+	gorm.Model
+
+	UserID     uint `gorm:"index"`
+	Category   string
+	Currency   string
+	Amount     decimal.Decimal `gorm:"type:varchar(64)"`
+	Thresholds IntList
+}
+
+type BudgetAlert struct {
+	gorm.Model
+
+	BudgetID  uint   `gorm:"uniqueIndex:idx_budget_alert"`
+	Period    string `gorm:"uniqueIndex:idx_budget_alert"`
+	Threshold int    `gorm:"uniqueIndex:idx_budget_alert"`
+}
+
+type DomainBudget domain.Budget
+
+func (b DomainBudget) ToBudget() Budget {
+	return Budget{
+		Model: gorm.Model{
+			ID: b.ID,
+		},
+		UserID:     b.UserID,
+		Category:   b.Category,
+		Currency:   b.Currency,
+		Amount:     b.Amount,
+		Thresholds: b.Thresholds,
+	}
+}
+
+func (b Budget) ToAPIMessage() domain.Budget {
+	return domain.Budget{
+		ID:         b.ID,
+		UserID:     b.UserID,
+		Category:   b.Category,
+		Currency:   b.Currency,
+		Amount:     b.Amount,
+		Thresholds: b.Thresholds,
+	}
+}
+
+type gormBudgetRepository struct {
+	db *gorm.DB
+}
+
+func (g *gormBudgetRepository) Migration(_ context.Context) error {
+	return g.db.AutoMigrate(&Budget{}, &BudgetAlert{})
+}
+
+func (g *gormBudgetRepository) ListByUser(ctx context.Context, userID uint) ([]domain.Budget, error) {
+	var items []Budget
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Where(&Budget{UserID: userID}).Order("id").Find(&items).Error
+	})
+	var result []domain.Budget
+	for _, v := range items {
+		result = append(result, v.ToAPIMessage())
+	}
+	return result, err
+}
+
+func (g *gormBudgetRepository) Store(ctx context.Context, budget *domain.Budget) error {
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		item := DomainBudget(*budget).ToBudget()
+		if err := db.Create(&item).Error; err != nil {
+			return err
+		}
+		budget.ID = item.ID
+		return nil
+	})
+}
+
+func (g *gormBudgetRepository) Delete(ctx context.Context, budget *domain.Budget) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("user_id = ? AND deleted_at IS NULL", budget.UserID).Delete(&Budget{}, budget.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrBudgetNotFound
+		}
+		return tx.Unscoped().Where(&BudgetAlert{BudgetID: budget.ID}).Delete(&BudgetAlert{}).Error
+	})
+}
+
+func (g *gormBudgetRepository) AlertSent(ctx context.Context, budgetID uint, period string, threshold int) (bool, error) {
+	var count int64
+	err := g.db.WithContext(ctx).Model(&BudgetAlert{}).
+		Where(&BudgetAlert{BudgetID: budgetID, Period: period, Threshold: threshold}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (g *gormBudgetRepository) StoreAlert(ctx context.Context, budgetID uint, period string, threshold int) error {
+	return g.db.WithContext(ctx).Create(&BudgetAlert{BudgetID: budgetID, Period: period, Threshold: threshold}).Error
+}
+
+func (g *gormBudgetRepository) wrapper(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return fn(g.db.WithContext(ctx).Model(&Budget{}))
+}
+
+func NewGormBudgetRepository(db *gorm.DB) domain.BudgetRepository {
+	return &gormBudgetRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type GormBudgetRepositoryTestSuite struct {
+	suite.Suite
+	Ctx  context.Context
+	DB   *gorm.DB
+	Repo domain.BudgetRepository
+}
+
+func (suite *GormBudgetRepositoryTestSuite) SetupTest() {
+	var (
+		err error
+	)
+
+	suite.DB, err = gorm.Open(sqlite.Open("file:budget?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	suite.NoError(err)
+
+	suite.DB = suite.DB.Debug()
+
+	suite.Repo = NewGormBudgetRepository(suite.DB)
+
+	suite.Ctx = context.Background()
+
+	suite.NoError(suite.Repo.Migration(suite.Ctx))
+}
+
+func Test_GormBudgetRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(GormBudgetRepositoryTestSuite))
+}
+
+func (suite *GormBudgetRepositoryTestSuite) Test_GormBudgetRepository() {
+	food := domain.Budget{
+		UserID:     1,
+		Category:   "Food",
+		Currency:   "AED",
+		Amount:     decimal.RequireFromString("1500.50"),
+		Thresholds: []int{80, 100},
+	}
+	overall := domain.Budget{
+		UserID:   2,
+		Amount:   decimal.NewFromInt(5000),
+		Currency: "AED",
+	}
+
+	suite.Run("store", func() {
+		suite.NoError(suite.Repo.Store(suite.Ctx, &food))
+		suite.NoError(suite.Repo.Store(suite.Ctx, &overall))
+		suite.NotZero(food.ID)
+	})
+
+	suite.Run("list by user", func() {
+		items, err := suite.Repo.ListByUser(suite.Ctx, 1)
+		if suite.NoError(err) && suite.Len(items, 1) {
+			suite.Equal("Food", items[0].Category)
+			suite.True(food.Amount.Equal(items[0].Amount))
+			suite.Equal([]int{80, 100}, items[0].Thresholds)
+		}
+	})
+
+	suite.Run("alerts", func() {
+		sent, err := suite.Repo.AlertSent(suite.Ctx, food.ID, "2020-11", 80)
+		suite.NoError(err)
+		suite.False(sent)
+
+		suite.NoError(suite.Repo.StoreAlert(suite.Ctx, food.ID, "2020-11", 80))
+		suite.Error(suite.Repo.StoreAlert(suite.Ctx, food.ID, "2020-11", 80), "duplicate")
+
+		sent, err = suite.Repo.AlertSent(suite.Ctx, food.ID, "2020-11", 80)
+		suite.NoError(err)
+		suite.True(sent)
+
+		sent, err = suite.Repo.AlertSent(suite.Ctx, food.ID, "2020-12", 80)
+		suite.NoError(err)
+		suite.False(sent)
+	})
+
+	suite.Run("delete other user", func() {
+		suite.Equal(ErrBudgetNotFound, suite.Repo.Delete(suite.Ctx, &domain.Budget{ID: food.ID, UserID: 2}))
+		items, err := suite.Repo.ListByUser(suite.Ctx, 1)
+		if suite.NoError(err) {
+			suite.Len(items, 1)
+		}
+	})
+
+	suite.Run("delete", func() {
+		suite.NoError(suite.Repo.Delete(suite.Ctx, &food))
+		items, err := suite.Repo.ListByUser(suite.Ctx, 1)
+		if suite.NoError(err) {
+			suite.Len(items, 0)
+		}
+		sent, err := suite.Repo.AlertSent(suite.Ctx, food.ID, "2020-11", 80)
+		suite.NoError(err)
+		suite.False(sent)
+		suite.Equal(ErrBudgetNotFound, suite.Repo.Delete(suite.Ctx, &food), "already deleted")
+	})
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"gorm.io/gorm"
+)
+
+type CategoryMapping struct {
+	gorm.Model
+
+	UserID   uint   `gorm:"uniqueIndex:idx_category_user_party"`
+	Party    string `gorm:"uniqueIndex:idx_category_user_party"`
+	Category string
+	Hits     int
+}
+
+type DomainCategoryMapping domain.CategoryMapping
+
+func (m DomainCategoryMapping) ToCategoryMapping() CategoryMapping {
+	return CategoryMapping{
+		UserID:   m.UserID,
+		Party:    m.Party,
+		Category: m.Category,
+		Hits:     m.Hits,
+	}
+}
+
+func (m CategoryMapping) ToAPIMessage() domain.CategoryMapping {
+	return domain.CategoryMapping{
+		UserID:   m.UserID,
+		Party:    m.Party,
+		Category: m.Category,
+		Hits:     m.Hits,
+	}
+}
+
+type gormCategoryRepository struct {
+	db *gorm.DB
+}
+
+func (g *gormCategoryRepository) Migration(_ context.Context) error {
+	return g.db.AutoMigrate(&CategoryMapping{})
+}
+
+func (g *gormCategoryRepository) Get(ctx context.Context, userID uint, party string) (domain.CategoryMapping, error) {
+	item := CategoryMapping{}
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Where(&CategoryMapping{UserID: userID, Party: party}).Take(&item).Error
+	})
+	return item.ToAPIMessage(), err
+}
+
+func (g *gormCategoryRepository) ListByUser(ctx context.Context, userID uint) ([]domain.CategoryMapping, error) {
+	var items []CategoryMapping
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Where(&CategoryMapping{UserID: userID}).Order("hits desc, id").Find(&items).Error
+	})
+	var result []domain.CategoryMapping
+	for _, v := range items {
+		result = append(result, v.ToAPIMessage())
+	}
+	return result, err
+}
+
+func (g *gormCategoryRepository) Store(ctx context.Context, mapping *domain.CategoryMapping) error {
+	item := CategoryMapping{}
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Where(&CategoryMapping{UserID: mapping.UserID, Party: mapping.Party}).Take(&item).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		item = DomainCategoryMapping(*mapping).ToCategoryMapping()
+		item.Hits = 1
+		err = g.wrapper(ctx, func(db *gorm.DB) error {
+			return db.Create(&item).Error
+		})
+	} else if err == nil {
+		if item.Category == mapping.Category {
+			item.Hits++
+		} else {
+			item.Category, item.Hits = mapping.Category, 1
+		}
+		err = g.wrapper(ctx, func(db *gorm.DB) error {
+			return db.Model(&item).Select("Category", "Hits").Updates(&item).Error
+		})
+	}
+	if err != nil {
+		return err
+	}
+	mapping.Hits = item.Hits
+	return nil
+}
+
+func (g *gormCategoryRepository) wrapper(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return fn(g.db.WithContext(ctx).Model(&CategoryMapping{}))
+}
+
+func NewGormCategoryRepository(db *gorm.DB) domain.CategoryRepository {
+	return &gormCategoryRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type GormCategoryRepositoryTestSuite struct {
+	suite.Suite
+	Ctx  context.Context
+	DB   *gorm.DB
+	Repo domain.CategoryRepository
+}
+
+func (suite *GormCategoryRepositoryTestSuite) SetupTest() {
+	var (
+		err error
+	)
+
+	suite.DB, err = gorm.Open(sqlite.Open("file:category?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	suite.NoError(err)
+
+	suite.DB = suite.DB.Debug()
+
+	suite.Repo = NewGormCategoryRepository(suite.DB)
+
+	suite.Ctx = context.Background()
+
+	suite.NoError(suite.Repo.Migration(suite.Ctx))
+}
+
+func Test_GormCategoryRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(GormCategoryRepositoryTestSuite))
+}
+
+func (suite *GormCategoryRepositoryTestSuite) Test_GormCategoryRepository() {
+	suite.Run("store", func() {
+		mapping := &domain.CategoryMapping{UserID: 1, Party: "uber", Category: "Transport"}
+		suite.NoError(suite.Repo.Store(suite.Ctx, mapping))
+		suite.Equal(1, mapping.Hits)
+
+		mapping = &domain.CategoryMapping{UserID: 1, Party: "uber", Category: "Transport"}
+		suite.NoError(suite.Repo.Store(suite.Ctx, mapping))
+		suite.Equal(2, mapping.Hits)
+
+		suite.NoError(suite.Repo.Store(suite.Ctx, &domain.CategoryMapping{UserID: 1, Party: "cafe", Category: "Food"}))
+		suite.NoError(suite.Repo.Store(suite.Ctx, &domain.CategoryMapping{UserID: 2, Party: "uber", Category: "Work"}))
+	})
+
+	suite.Run("get", func() {
+		item, err := suite.Repo.Get(suite.Ctx, 1, "uber")
+		suite.NoError(err)
+		suite.Equal(domain.CategoryMapping{UserID: 1, Party: "uber", Category: "Transport", Hits: 2}, item)
+
+		_, err = suite.Repo.Get(suite.Ctx, 1, "taxi")
+		suite.True(errors.Is(err, gorm.ErrRecordNotFound))
+	})
+
+	suite.Run("correction", func() {
+		mapping := &domain.CategoryMapping{UserID: 1, Party: "uber", Category: "Work"}
+		suite.NoError(suite.Repo.Store(suite.Ctx, mapping))
+		suite.Equal(1, mapping.Hits)
+
+		item, err := suite.Repo.Get(suite.Ctx, 1, "uber")
+		suite.NoError(err)
+		suite.Equal("Work", item.Category)
+	})
+
+	suite.Run("list by user", func() {
+		items, err := suite.Repo.ListByUser(suite.Ctx, 1)
+		suite.NoError(err)
+		suite.Len(items, 2)
+	})
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Fingerprint struct {
+	gorm.Model
+
+	UserID uint   `gorm:"uniqueIndex:idx_fingerprint_user_hash"`
+	Hash   string `gorm:"uniqueIndex:idx_fingerprint_user_hash"`
+}
+
+type gormFingerprintRepository struct {
+	db *gorm.DB
+}
+
+func (g *gormFingerprintRepository) Migration(_ context.Context) error {
+	return g.db.AutoMigrate(&Fingerprint{})
+}
+
+func (g *gormFingerprintRepository) Exists(ctx context.Context, userID uint, hash string) (bool, error) {
+	var count int64
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Where(&Fingerprint{UserID: userID, Hash: hash}).Count(&count).Error
+	})
+	return count > 0, err
+}
+
+func (g *gormFingerprintRepository) Store(ctx context.Context, userID uint, hash string) error {
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Create(&Fingerprint{UserID: userID, Hash: hash}).Error
+	})
+}
+
+func (g *gormFingerprintRepository) Reserve(ctx context.Context, userID uint, hash string) (bool, error) {
+	var reserved bool
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&Fingerprint{UserID: userID, Hash: hash})
+		reserved = res.RowsAffected == 1
+		return res.Error
+	})
+	return reserved, err
+}
+
+func (g *gormFingerprintRepository) Delete(ctx context.Context, userID uint, hash string) error {
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Unscoped().Where(&Fingerprint{UserID: userID, Hash: hash}).Delete(&Fingerprint{}).Error
+	})
+}
+
+func (g *gormFingerprintRepository) wrapper(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return fn(g.db.WithContext(ctx).Model(&Fingerprint{}))
+}
+
+func NewGormFingerprintRepository(db *gorm.DB) domain.FingerprintRepository {
+	return &gormFingerprintRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type GormFingerprintRepositoryTestSuite struct {
+	suite.Suite
+	Ctx  context.Context
+	DB   *gorm.DB
+	Repo domain.FingerprintRepository
+}
+
+func (suite *GormFingerprintRepositoryTestSuite) SetupTest() {
+	var (
+		err error
+	)
+
+	suite.DB, err = gorm.Open(sqlite.Open("file:fingerprint?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	suite.NoError(err)
+
+	suite.DB = suite.DB.Debug()
+
+	suite.Repo = NewGormFingerprintRepository(suite.DB)
+
+	suite.Ctx = context.Background()
+
+	suite.NoError(suite.Repo.Migration(suite.Ctx))
+}
+
+func Test_GormFingerprintRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(GormFingerprintRepositoryTestSuite))
+}
+
+func (suite *GormFingerprintRepositoryTestSuite) Test_GormFingerprintRepository() {
+	suite.Run("store", func() {
+		suite.NoError(suite.Repo.Store(suite.Ctx, 1, "hash1"))
+		suite.Error(suite.Repo.Store(suite.Ctx, 1, "hash1"), "duplicate")
+		suite.NoError(suite.Repo.Store(suite.Ctx, 2, "hash1"))
+	})
+
+	suite.Run("exists", func() {
+		ok, err := suite.Repo.Exists(suite.Ctx, 1, "hash1")
+		suite.NoError(err)
+		suite.True(ok)
+
+		ok, err = suite.Repo.Exists(suite.Ctx, 1, "hash2")
+		suite.NoError(err)
+		suite.False(ok)
+	})
+
+	suite.Run("reserve", func() {
+		ok, err := suite.Repo.Reserve(suite.Ctx, 1, "hash3")
+		suite.NoError(err)
+		suite.True(ok)
+
+		ok, err = suite.Repo.Reserve(suite.Ctx, 1, "hash3")
+		suite.NoError(err)
+		suite.False(ok, "already reserved")
+	})
+
+	suite.Run("delete", func() {
+		suite.NoError(suite.Repo.Delete(suite.Ctx, 1, "hash1"))
+		ok, err := suite.Repo.Exists(suite.Ctx, 1, "hash1")
+		suite.NoError(err)
+		suite.False(ok)
+		suite.NoError(suite.Repo.Store(suite.Ctx, 1, "hash1"))
+	})
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"gorm.io/gorm"
+)
+
+type OutboxTransaction domain.Transaction
+
+type OutboxItem struct {
+	gorm.Model
+
+	UserID      uint   `gorm:"index"`
+	Sink        string `gorm:"index"`
+	Transaction OutboxTransaction
+	Attempts    int
+	NextAttempt time.Time `gorm:"index"`
+	LastError   string
+	Dead        bool `gorm:"not null;default:false"`
+}
+
+type DomainOutboxItem domain.OutboxItem
+
+func (i DomainOutboxItem) ToOutboxItem() OutboxItem {
+	return OutboxItem{
+		Model: gorm.Model{
+			ID:        i.ID,
+			CreatedAt: i.CreatedAt,
+		},
+		UserID:      i.UserID,
+		Sink:        i.Sink,
+		Transaction: OutboxTransaction(i.Transaction),
+		Attempts:    i.Attempts,
+		NextAttempt: i.NextAttempt,
+		LastError:   i.LastError,
+		Dead:        i.Dead,
+	}
+}
+
+func (i OutboxItem) ToAPIMessage() domain.OutboxItem {
+	return domain.OutboxItem{
+		ID:          i.ID,
+		UserID:      i.UserID,
+		Sink:        i.Sink,
+		Transaction: domain.Transaction(i.Transaction),
+		Attempts:    i.Attempts,
+		NextAttempt: i.NextAttempt,
+		LastError:   i.LastError,
+		Dead:        i.Dead,
+		CreatedAt:   i.CreatedAt,
+	}
+}
+
+func (t *OutboxTransaction) Scan(value interface{}) (err error) {
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("failed to unmarshal JSON value: %v", value)
+	}
+	return json.Unmarshal(bytes, t)
+}
+
+func (t OutboxTransaction) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+func (OutboxTransaction) GormDataType() string {
+	return "string"
+}
+
+type gormOutboxRepository struct {
+	db *gorm.DB
+}
+
+func (g *gormOutboxRepository) Migration(_ context.Context) error {
+	return g.db.AutoMigrate(&OutboxItem{})
+}
+
+func (g *gormOutboxRepository) Get(ctx context.Context, id uint) (domain.OutboxItem, error) {
+	item := OutboxItem{}
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Where(&OutboxItem{Model: gorm.Model{ID: id}}).Take(&item).Error
+	})
+	return item.ToAPIMessage(), err
+}
+
+func (g *gormOutboxRepository) ListByUser(ctx context.Context, userID uint) ([]domain.OutboxItem, error) {
+	var items []OutboxItem
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Where(&OutboxItem{UserID: userID}).Order("id").Find(&items).Error
+	})
+	return toAPIOutboxItems(items), err
+}
+
+func (g *gormOutboxRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]domain.OutboxItem, error) {
+	var items []OutboxItem
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Where("next_attempt <= ? AND dead = ?", now, false).Order("next_attempt").Limit(limit).Find(&items).Error
+	})
+	return toAPIOutboxItems(items), err
+}
+
+func (g *gormOutboxRepository) Store(ctx context.Context, item *domain.OutboxItem) error {
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		row := DomainOutboxItem(*item).ToOutboxItem()
+		if err := db.Create(&row).Error; err != nil {
+			return err
+		}
+		item.ID = row.ID
+		item.CreatedAt = row.CreatedAt
+		return nil
+	})
+}
+
+func (g *gormOutboxRepository) Update(ctx context.Context, item *domain.OutboxItem) error {
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			row := DomainOutboxItem(*item).ToOutboxItem()
+			return tx.Take(&OutboxItem{}, item.ID).
+				Select("Transaction", "Attempts", "NextAttempt", "LastError", "Dead").
+				Updates(&row).Error
+		})
+	})
+}
+
+func (g *gormOutboxRepository) Claim(ctx context.Context, item *domain.OutboxItem, until time.Time) (bool, error) {
+	var claimed bool
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		res := db.Where("id = ? AND attempts = ?", item.ID, item.Attempts).
+			Updates(map[string]interface{}{"attempts": item.Attempts + 1, "next_attempt": until})
+		claimed = res.RowsAffected == 1
+		return res.Error
+	})
+	if err == nil && claimed {
+		item.Attempts++
+		item.NextAttempt = until
+	}
+	return claimed, err
+}
+
+func (g *gormOutboxRepository) Delete(ctx context.Context, item *domain.OutboxItem) error {
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Delete(&OutboxItem{}, item.ID).Error
+	})
+}
+
+func (g *gormOutboxRepository) wrapper(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return fn(g.db.WithContext(ctx).Model(&OutboxItem{}))
+}
+
+func toAPIOutboxItems(items []OutboxItem) []domain.OutboxItem {
+	var result []domain.OutboxItem
+	for _, v := range items {
+		result = append(result, v.ToAPIMessage())
+	}
+	return result
+}
+
+func NewGormOutboxRepository(db *gorm.DB) domain.OutboxRepository {
+	return &gormOutboxRepository{
+		db: db,
+	}
+}
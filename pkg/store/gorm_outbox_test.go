@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type GormOutboxRepositoryTestSuite struct {
+	suite.Suite
+	Ctx  context.Context
+	DB   *gorm.DB
+	Repo domain.OutboxRepository
+}
+
+func (suite *GormOutboxRepositoryTestSuite) SetupTest() {
+	var (
+		err error
+	)
+
+	suite.DB, err = gorm.Open(sqlite.Open("file:outbox?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	suite.NoError(err)
+
+	suite.DB = suite.DB.Debug()
+
+	suite.Repo = NewGormOutboxRepository(suite.DB)
+
+	suite.Ctx = context.Background()
+
+	suite.NoError(suite.Repo.Migration(suite.Ctx))
+}
+
+func Test_GormOutboxRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(GormOutboxRepositoryTestSuite))
+}
+
+func (suite *GormOutboxRepositoryTestSuite) Test_GormOutboxRepository() {
+	now := time.Date(2020, 11, 1, 12, 0, 0, 0, time.UTC)
+
+	due := domain.OutboxItem{
+		UserID: 1,
+		Sink:   "google",
+		Transaction: domain.Transaction{
+			UserID: 1,
+			Party:  "FACEBK",
+			Amount: decimal.RequireFromString("1123.33"),
+		},
+		NextAttempt: now.Add(-time.Minute),
+		LastError:   "quota",
+	}
+	later := domain.OutboxItem{
+		UserID:      1,
+		Sink:        "google",
+		NextAttempt: now.Add(time.Hour),
+	}
+
+	suite.Run("store", func() {
+		suite.NoError(suite.Repo.Store(suite.Ctx, &due))
+		suite.NoError(suite.Repo.Store(suite.Ctx, &later))
+		suite.NotZero(due.ID)
+		suite.NotZero(later.ID)
+	})
+
+	suite.Run("list due", func() {
+		items, err := suite.Repo.ListDue(suite.Ctx, now, 10)
+		if suite.NoError(err) && suite.Len(items, 1) {
+			suite.Equal(due.ID, items[0].ID)
+			suite.Equal("FACEBK", items[0].Transaction.Party)
+			suite.True(due.Transaction.Amount.Equal(items[0].Transaction.Amount))
+		}
+	})
+
+	suite.Run("update", func() {
+		due.Attempts = 1
+		due.NextAttempt = now.Add(time.Minute)
+		due.LastError = "network"
+		due.Transaction.Category = "Ads"
+		suite.NoError(suite.Repo.Update(suite.Ctx, &due))
+
+		item, err := suite.Repo.Get(suite.Ctx, due.ID)
+		if suite.NoError(err) {
+			suite.Equal("Ads", item.Transaction.Category)
+			suite.Equal(1, item.Attempts)
+			suite.Equal("network", item.LastError)
+			suite.True(due.NextAttempt.Equal(item.NextAttempt))
+		}
+	})
+
+	suite.Run("claim", func() {
+		stale := due
+		suite.True(suite.Repo.Claim(suite.Ctx, &due, now.Add(time.Hour)))
+		suite.Equal(2, due.Attempts)
+
+		claimed, err := suite.Repo.Claim(suite.Ctx, &stale, now.Add(time.Hour))
+		if suite.NoError(err) {
+			suite.False(claimed, "item is already claimed")
+		}
+		suite.Equal(1, stale.Attempts)
+
+		items, err := suite.Repo.ListDue(suite.Ctx, now.Add(time.Minute), 10)
+		if suite.NoError(err) {
+			suite.Len(items, 0)
+		}
+	})
+
+	suite.Run("dead", func() {
+		later.Dead = true
+		later.NextAttempt = now.Add(-time.Hour)
+		suite.NoError(suite.Repo.Update(suite.Ctx, &later))
+
+		items, err := suite.Repo.ListDue(suite.Ctx, now.Add(30*time.Minute), 10)
+		if suite.NoError(err) {
+			suite.Len(items, 0, "dead items are not retried")
+		}
+		item, err := suite.Repo.Get(suite.Ctx, later.ID)
+		if suite.NoError(err) {
+			suite.True(item.Dead)
+		}
+	})
+
+	suite.Run("list by user", func() {
+		items, err := suite.Repo.ListByUser(suite.Ctx, 1)
+		if suite.NoError(err) {
+			suite.Len(items, 2)
+		}
+	})
+
+	suite.Run("delete", func() {
+		suite.NoError(suite.Repo.Delete(suite.Ctx, &due))
+		suite.NoError(suite.Repo.Delete(suite.Ctx, &later))
+		items, err := suite.Repo.ListByUser(suite.Ctx, 1)
+		if suite.NoError(err) {
+			suite.Len(items, 0)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"gorm.io/gorm"
+)
+
+type Rate struct {
+	gorm.Model
+
+	Base  string          `gorm:"uniqueIndex:idx_rate"`
+	Quote string          `gorm:"uniqueIndex:idx_rate"`
+	Date  time.Time       `gorm:"uniqueIndex:idx_rate"`
+	Rate  decimal.Decimal `gorm:"type:varchar(64)"`
+}
+
+type DomainRate domain.Rate
+
+func (r DomainRate) ToRate() Rate {
+	return Rate{
+		Base:  strings.ToUpper(r.Base),
+		Quote: strings.ToUpper(r.Quote),
+		Date:  rateDay(r.Date),
+		Rate:  r.Rate,
+	}
+}
+
+func (r Rate) ToAPIMessage() domain.Rate {
+	return domain.Rate{
+		Base:  r.Base,
+		Quote: r.Quote,
+		Date:  r.Date,
+		Rate:  r.Rate,
+	}
+}
+
+func rateDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+type gormRateRepository struct {
+	db *gorm.DB
+}
+
+func (g *gormRateRepository) Migration(_ context.Context) error {
+	return g.db.AutoMigrate(&Rate{})
+}
+
+func (g *gormRateRepository) Get(ctx context.Context, base, quote string, date time.Time) (domain.Rate, error) {
+	item := Rate{}
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Where("base = ? AND quote = ? AND date <= ?", strings.ToUpper(base), strings.ToUpper(quote), rateDay(date)).
+			Order("date desc").Take(&item).Error
+	})
+	return item.ToAPIMessage(), err
+}
+
+func (g *gormRateRepository) Store(ctx context.Context, rate *domain.Rate) error {
+	item := DomainRate(*rate).ToRate()
+	existing := Rate{}
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Where(&Rate{Base: item.Base, Quote: item.Quote, Date: item.Date}).Take(&existing).Error
+	})
+	switch {
+	case err == nil:
+		existing.Rate = item.Rate
+		return g.wrapper(ctx, func(db *gorm.DB) error {
+			return db.Model(&existing).Select("Rate").Updates(&existing).Error
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return g.wrapper(ctx, func(db *gorm.DB) error {
+			return db.Create(&item).Error
+		})
+	}
+	return err
+}
+
+func (g *gormRateRepository) wrapper(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return fn(g.db.WithContext(ctx).Model(&Rate{}))
+}
+
+func NewGormRateRepository(db *gorm.DB) domain.RateRepository {
+	return &gormRateRepository{
+		db: db,
+	}
+}
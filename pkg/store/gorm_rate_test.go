@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type GormRateRepositoryTestSuite struct {
+	suite.Suite
+	Ctx  context.Context
+	DB   *gorm.DB
+	Repo domain.RateRepository
+}
+
+func (suite *GormRateRepositoryTestSuite) SetupTest() {
+	var (
+		err error
+	)
+
+	suite.DB, err = gorm.Open(sqlite.Open("file:rate?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	suite.NoError(err)
+
+	suite.DB = suite.DB.Debug()
+
+	suite.Repo = NewGormRateRepository(suite.DB)
+
+	suite.Ctx = context.Background()
+
+	suite.NoError(suite.Repo.Migration(suite.Ctx))
+}
+
+func Test_GormRateRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(GormRateRepositoryTestSuite))
+}
+
+func (suite *GormRateRepositoryTestSuite) Test_GormRateRepository() {
+	date := func(day int) time.Time {
+		return time.Date(2020, 11, day, 15, 30, 0, 0, time.UTC)
+	}
+
+	suite.Run("store", func() {
+		suite.NoError(suite.Repo.Store(suite.Ctx, &domain.Rate{Base: "usd", Quote: "AED", Date: date(1), Rate: decimal.RequireFromString("3.67")}))
+		suite.NoError(suite.Repo.Store(suite.Ctx, &domain.Rate{Base: "USD", Quote: "AED", Date: date(5), Rate: decimal.RequireFromString("3.6")}))
+		suite.NoError(suite.Repo.Store(suite.Ctx, &domain.Rate{Base: "USD", Quote: "AED", Date: date(5), Rate: decimal.RequireFromString("3.6725")}), "replace")
+	})
+
+	suite.Run("get", func() {
+		tests := []struct {
+			date time.Time
+			want string
+		}{
+			{date: date(1), want: "3.67"},
+			{date: date(4), want: "3.67"},
+			{date: time.Date(2020, 11, 5, 0, 0, 0, 0, time.UTC), want: "3.6725"},
+			{date: date(20), want: "3.6725"},
+		}
+		for _, tt := range tests {
+			got, err := suite.Repo.Get(suite.Ctx, "USD", "aed", tt.date)
+			if suite.NoError(err) {
+				suite.True(decimal.RequireFromString(tt.want).Equal(got.Rate), "%s: %s", tt.date, got.Rate)
+			}
+		}
+	})
+
+	suite.Run("not found", func() {
+		_, err := suite.Repo.Get(suite.Ctx, "USD", "AED", time.Date(2020, 10, 31, 0, 0, 0, 0, time.UTC))
+		suite.True(errors.Is(err, gorm.ErrRecordNotFound))
+		_, err = suite.Repo.Get(suite.Ctx, "EUR", "AED", date(5))
+		suite.True(errors.Is(err, gorm.ErrRecordNotFound))
+	})
+}
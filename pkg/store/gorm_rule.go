@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"gorm.io/gorm"
+)
+
+var ErrRuleNotFound = errors.New("store: rule not found")
+
+type Rule struct {
+	gorm.Model
+
+	UserID     uint `gorm:"index"`
+	Party      string
+	PartyRegex string
+	MinAmount  decimal.NullDecimal `gorm:"type:varchar(64)"`
+	MaxAmount  decimal.NullDecimal `gorm:"type:varchar(64)"`
+	Account    string
+	Direction  string
+	Category   string
+	Tags       StringList
+}
+
+type DomainRule domain.Rule
+
+func (r DomainRule) ToRule() Rule {
+	return Rule{
+		Model: gorm.Model{
+			ID: r.ID,
+		},
+		UserID:     r.UserID,
+		Party:      r.Party,
+		PartyRegex: r.PartyRegex,
+		MinAmount:  r.MinAmount,
+		MaxAmount:  r.MaxAmount,
+		Account:    r.Account,
+		Direction:  r.Direction,
+		Category:   r.Category,
+		Tags:       r.Tags,
+	}
+}
+
+func (r Rule) ToAPIMessage() domain.Rule {
+	return domain.Rule{
+		ID:         r.ID,
+		UserID:     r.UserID,
+		Party:      r.Party,
+		PartyRegex: r.PartyRegex,
+		MinAmount:  r.MinAmount,
+		MaxAmount:  r.MaxAmount,
+		Account:    r.Account,
+		Direction:  r.Direction,
+		Category:   r.Category,
+		Tags:       r.Tags,
+	}
+}
+
+type gormRuleRepository struct {
+	db *gorm.DB
+}
+
+func (g *gormRuleRepository) Migration(_ context.Context) error {
+	return g.db.AutoMigrate(&Rule{})
+}
+
+func (g *gormRuleRepository) ListByUser(ctx context.Context, userID uint) ([]domain.Rule, error) {
+	var items []Rule
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Where(&Rule{UserID: userID}).Order("id").Find(&items).Error
+	})
+	var result []domain.Rule
+	for _, v := range items {
+		result = append(result, v.ToAPIMessage())
+	}
+	return result, err
+}
+
+func (g *gormRuleRepository) Store(ctx context.Context, rule *domain.Rule) error {
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		item := DomainRule(*rule).ToRule()
+		if err := db.Create(&item).Error; err != nil {
+			return err
+		}
+		rule.ID = item.ID
+		return nil
+	})
+}
+
+func (g *gormRuleRepository) Delete(ctx context.Context, rule *domain.Rule) error {
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		res := db.Where("user_id = ? AND deleted_at IS NULL", rule.UserID).Delete(&Rule{}, rule.ID)
+		if res.Error == nil && res.RowsAffected == 0 {
+			return ErrRuleNotFound
+		}
+		return res.Error
+	})
+}
+
+func (g *gormRuleRepository) wrapper(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return fn(g.db.WithContext(ctx).Model(&Rule{}))
+}
+
+func NewGormRuleRepository(db *gorm.DB) domain.RuleRepository {
+	return &gormRuleRepository{
+		db: db,
+	}
+}
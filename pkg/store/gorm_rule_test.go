@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type GormRuleRepositoryTestSuite struct {
+	suite.Suite
+	Ctx  context.Context
+	DB   *gorm.DB
+	Repo domain.RuleRepository
+}
+
+func (suite *GormRuleRepositoryTestSuite) SetupTest() {
+	var (
+		err error
+	)
+
+	suite.DB, err = gorm.Open(sqlite.Open("file:rule?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	suite.NoError(err)
+
+	suite.DB = suite.DB.Debug()
+
+	suite.Repo = NewGormRuleRepository(suite.DB)
+
+	suite.Ctx = context.Background()
+
+	suite.NoError(suite.Repo.Migration(suite.Ctx))
+}
+
+func Test_GormRuleRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(GormRuleRepositoryTestSuite))
+}
+
+func (suite *GormRuleRepositoryTestSuite) Test_GormRuleRepository() {
+	taxi := domain.Rule{
+		UserID:    1,
+		Party:     "uber",
+		MaxAmount: decimal.NullDecimal{Decimal: decimal.NewFromInt(50), Valid: true},
+		Category:  "Transport",
+		Tags:      []string{"taxi", "work"},
+	}
+	other := domain.Rule{
+		UserID:   2,
+		Party:    "cafe",
+		Category: "Food",
+	}
+
+	suite.Run("store", func() {
+		suite.NoError(suite.Repo.Store(suite.Ctx, &taxi))
+		suite.NoError(suite.Repo.Store(suite.Ctx, &other))
+		suite.NotZero(taxi.ID)
+	})
+
+	suite.Run("list by user", func() {
+		items, err := suite.Repo.ListByUser(suite.Ctx, 1)
+		if suite.NoError(err) && suite.Len(items, 1) {
+			suite.Equal(taxi.ID, items[0].ID)
+			suite.Equal("uber", items[0].Party)
+			suite.False(items[0].MinAmount.Valid)
+			suite.True(items[0].MaxAmount.Valid)
+			suite.True(taxi.MaxAmount.Decimal.Equal(items[0].MaxAmount.Decimal))
+			suite.Equal([]string{"taxi", "work"}, items[0].Tags)
+		}
+	})
+
+	suite.Run("delete other user", func() {
+		suite.Equal(ErrRuleNotFound, suite.Repo.Delete(suite.Ctx, &domain.Rule{ID: other.ID, UserID: 1}))
+		items, err := suite.Repo.ListByUser(suite.Ctx, 2)
+		if suite.NoError(err) {
+			suite.Len(items, 1)
+		}
+	})
+
+	suite.Run("delete", func() {
+		suite.NoError(suite.Repo.Delete(suite.Ctx, &taxi))
+		items, err := suite.Repo.ListByUser(suite.Ctx, 1)
+		if suite.NoError(err) {
+			suite.Len(items, 0)
+		}
+		suite.Equal(ErrRuleNotFound, suite.Repo.Delete(suite.Ctx, &taxi), "already deleted")
+	})
+}
@@ -1,12 +1,16 @@
 package store
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 
 	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/ftomza/go-bank-bot/pkg/secret"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -110,6 +114,35 @@ func (suite *GormUserRepositoryTestSuite) Test_GormUserRepository_GetByBotUserID
 	})
 }
 
+func (suite *GormUserRepositoryTestSuite) Test_GormUserRepository_List() {
+	suite.Run("ok", func() {
+		item := domain.User{
+			ID:             20,
+			BotUserID:      30,
+			DigestSchedule: "weekly",
+			DigestTime:     "09:30",
+			LastDigestAt:   time.Date(2020, 11, 2, 9, 30, 0, 0, time.UTC),
+		}
+		err := suite.Repo.Store(suite.Ctx, &item)
+		if suite.NoError(err, "Store") {
+			users, err := suite.Repo.List(suite.Ctx)
+			if suite.NoError(err) {
+				var found *domain.User
+				for i, v := range users {
+					if v.ID == item.ID {
+						found = &users[i]
+					}
+				}
+				if suite.NotNil(found) {
+					suite.Equal(item.DigestSchedule, found.DigestSchedule)
+					suite.Equal(item.DigestTime, found.DigestTime)
+					suite.True(item.LastDigestAt.Equal(found.LastDigestAt))
+				}
+			}
+		}
+	})
+}
+
 func (suite *GormUserRepositoryTestSuite) Test_GormUserRepository_Update() {
 	suite.Run("ok", func() {
 		item := domain.User{
@@ -141,3 +174,184 @@ func (suite *GormUserRepositoryTestSuite) Test_GormUserRepository_Update() {
 		suite.EqualError(err, "record not found", "Store")
 	})
 }
+
+func (suite *GormUserRepositoryTestSuite) Test_GormUserRepository_UpdateFields() {
+	suite.Run("ok", func() {
+		item := domain.User{
+			ID:        40,
+			BotUserID: 41,
+			Timezone:  "Asia/Dubai",
+		}
+		if !suite.NoError(suite.Repo.Store(suite.Ctx, &item), "Store") {
+			return
+		}
+		stale := item
+
+		item.DigestSchedule = "daily"
+		item.NumberFormat = &domain.NumberFormat{Decimal: ",", Group: "."}
+		suite.NoError(suite.Repo.UpdateFields(suite.Ctx, &item, "DigestSchedule", "NumberFormat"))
+
+		stale.TokSheet = []byte("token")
+		stale.Timezone = ""
+		suite.NoError(suite.Repo.UpdateFields(suite.Ctx, &stale, "TokSheet"))
+
+		user, err := suite.Repo.Get(suite.Ctx, 40)
+		if suite.NoError(err) {
+			suite.Equal([]byte("token"), user.TokSheet)
+			suite.Equal("daily", user.DigestSchedule, "stale snapshot must not revert other fields")
+			suite.Equal("Asia/Dubai", user.Timezone)
+			suite.Equal(&domain.NumberFormat{Decimal: ",", Group: "."}, user.NumberFormat)
+		}
+	})
+
+	suite.Run("fail", func() {
+		suite.Error(suite.Repo.UpdateFields(suite.Ctx, &domain.User{ID: 40}))
+		suite.EqualError(suite.Repo.UpdateFields(suite.Ctx, &domain.User{ID: 99}, "SheetID"), "record not found")
+	})
+}
+
+func (suite *GormUserRepositoryTestSuite) Test_GormUserRepository_Update_ZeroValues() {
+	suite.Run("ok", func() {
+		item := domain.User{
+			ID:              6,
+			BotUserID:       18,
+			SheetID:         "test",
+			AllowDuplicates: true,
+		}
+		err := suite.Repo.Store(suite.Ctx, &item)
+		if !suite.NoError(err, "Store") {
+			return
+		}
+		item.SheetID = ""
+		item.AllowDuplicates = false
+		if suite.NoError(suite.Repo.Update(suite.Ctx, &item)) {
+			user, err := suite.Repo.Get(suite.Ctx, 6)
+			if suite.NoError(err) {
+				suite.Equal("", user.SheetID)
+				suite.False(user.AllowDuplicates)
+			}
+		}
+	})
+}
+
+func (suite *GormUserRepositoryTestSuite) Test_GormUserRepository_Migration_TrxPatterns() {
+	suite.Run("ok", func() {
+		suite.NoError(suite.DB.Exec(
+			"INSERT INTO users (id, bot_user_id, trx_patterns) VALUES (?, ?, ?)",
+			7, 19, `[{"pattern":"ptr1"},{"pattern":"ptr2","preset":"enbd-card","version":1},{"pattern":"ptr3","name":"Card"}]`,
+		).Error)
+
+		suite.NoError(suite.Repo.Migration(suite.Ctx))
+
+		user, err := suite.Repo.Get(suite.Ctx, 7)
+		if suite.NoError(err) && suite.Len(user.TrxPatterns, 3) {
+			suite.Equal("Pattern 1", user.TrxPatterns[0].Name)
+			suite.Equal("enbd-card", user.TrxPatterns[1].Name)
+			suite.Equal("Card", user.TrxPatterns[2].Name)
+			suite.Equal("ptr3", user.TrxPatterns[2].Pattern)
+		}
+	})
+}
+
+type GormUserRepositoryCipherTestSuite struct {
+	suite.Suite
+	Ctx     context.Context
+	DB      *gorm.DB
+	Keyring *secret.Keyring
+	Repo    domain.UserRepository
+}
+
+func (suite *GormUserRepositoryCipherTestSuite) SetupTest() {
+	var (
+		err error
+	)
+
+	suite.DB, err = gorm.Open(sqlite.Open("file:cipher?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	suite.NoError(err)
+
+	suite.DB = suite.DB.Debug()
+
+	suite.Keyring, err = secret.NewKeyring(bytes.Repeat([]byte{1}, secret.KeySize))
+	suite.NoError(err)
+
+	suite.Repo = NewGormUserRepository(suite.DB, WithCipher(suite.Keyring))
+
+	suite.Ctx = context.Background()
+
+	suite.NoError(NewGormUserRepository(suite.DB).Migration(suite.Ctx))
+	suite.NoError(suite.DB.Exec("DELETE FROM users").Error)
+}
+
+func Test_GormUserRepositoryCipherTestSuite(t *testing.T) {
+	suite.Run(t, new(GormUserRepositoryCipherTestSuite))
+}
+
+func (suite *GormUserRepositoryCipherTestSuite) rawToken(id uint) []byte {
+	var user User
+	suite.NoError(suite.DB.Unscoped().Model(&User{}).Where("id = ?", id).Take(&user).Error)
+	return user.TokSheet
+}
+
+func (suite *GormUserRepositoryCipherTestSuite) Test_Migration() {
+	token := []byte(`{"access_token":"a","refresh_token":"r"}`)
+	suite.NoError(suite.DB.Exec("INSERT INTO users (id, bot_user_id, tok_sheet) VALUES (?, ?, ?), (?, ?, NULL)", 1, 11, token, 2, 12).Error)
+	suite.NoError(suite.DB.Exec("INSERT INTO users (id, bot_user_id, tok_sheet, deleted_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)", 4, 14, token).Error)
+	suite.NoError(suite.DB.Exec("CREATE INDEX IF NOT EXISTS idx_users_tok_sheet ON users(tok_sheet)").Error)
+
+	suite.NoError(suite.Repo.Migration(suite.Ctx))
+	suite.False(suite.DB.Migrator().HasIndex(&User{}, "idx_users_tok_sheet"))
+
+	raw := suite.rawToken(1)
+	suite.True(suite.Keyring.Encrypted(raw))
+	suite.NotContains(string(raw), "refresh_token")
+	suite.Nil(suite.rawToken(2))
+	suite.True(suite.Keyring.Encrypted(suite.rawToken(4)), "soft-deleted users are encrypted too")
+
+	suite.NoError(suite.Repo.Migration(suite.Ctx))
+	suite.Equal(raw, suite.rawToken(1), "already encrypted rows are kept")
+
+	user, err := suite.Repo.Get(suite.Ctx, 1)
+	if suite.NoError(err) {
+		suite.Equal(token, user.TokSheet)
+	}
+}
+
+func (suite *GormUserRepositoryCipherTestSuite) Test_StoreUpdateRotate() {
+	token := []byte(`{"access_token":"b"}`)
+	suite.NoError(suite.Repo.Store(suite.Ctx, &domain.User{ID: 3, BotUserID: 13, TokSheet: token}))
+	suite.True(suite.Keyring.Encrypted(suite.rawToken(3)))
+
+	user, err := suite.Repo.GetByBotUserID(suite.Ctx, 13)
+	if suite.NoError(err) {
+		suite.Equal(token, user.TokSheet)
+	}
+
+	user.SheetID = "sheet"
+	suite.NoError(suite.Repo.Update(suite.Ctx, &user))
+	users, err := suite.Repo.List(suite.Ctx)
+	if suite.NoError(err) && suite.Len(users, 1) {
+		suite.Equal(token, users[0].TokSheet)
+		suite.Equal("sheet", users[0].SheetID)
+	}
+
+	_, err = NewGormUserRepository(suite.DB).Get(suite.Ctx, 3)
+	suite.NoError(err, "repository without cipher returns raw value")
+
+	rotated, err := secret.NewKeyring(bytes.Repeat([]byte{2}, secret.KeySize), bytes.Repeat([]byte{1}, secret.KeySize))
+	suite.NoError(err)
+	count, err := RotateGormUserTokens(suite.Ctx, suite.DB, rotated)
+	suite.NoError(err)
+	suite.Equal(1, count)
+
+	id, err := rotated.KeyID(suite.rawToken(3))
+	if suite.NoError(err) {
+		suite.Equal(rotated.Primary(), id)
+	}
+	_, err = suite.Repo.Get(suite.Ctx, 3)
+	suite.True(errors.Is(err, secret.ErrUnknownKey), "old keyring can't read rotated token: %v", err)
+
+	user, err = NewGormUserRepository(suite.DB, WithCipher(rotated)).Get(suite.Ctx, 3)
+	if suite.NoError(err) {
+		suite.Equal(token, user.TokSheet)
+	}
+}
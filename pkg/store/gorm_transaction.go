@@ -0,0 +1,205 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"gorm.io/gorm"
+)
+
+type Transaction struct {
+	gorm.Model
+
+	TransactionID string `gorm:"index"`
+	UserID        uint   `gorm:"index"`
+	Account       string `gorm:"index"`
+	Party         string
+	Direction     string
+	Amount        decimal.Decimal `gorm:"type:varchar(64)"`
+	Currency      string
+	Date          time.Time       `gorm:"index"`
+	Total         decimal.Decimal `gorm:"type:varchar(64)"`
+	Raw           string
+	Category      string `gorm:"index"`
+	Tags          StringList
+	Refs          StringMap
+	BaseAmount    decimal.Decimal `gorm:"type:varchar(64)"`
+	BaseCurrency  string
+}
+
+type DomainTransaction domain.Transaction
+
+func (t DomainTransaction) ToTransaction() Transaction {
+	return Transaction{
+		TransactionID: t.ID,
+		UserID:        t.UserID,
+		Account:       t.Account,
+		Party:         t.Party,
+		Direction:     t.Direction,
+		Amount:        t.Amount,
+		Currency:      t.Currency,
+		Date:          t.Date.UTC(),
+		Total:         t.Total,
+		Raw:           t.Raw,
+		Category:      t.Category,
+		Tags:          t.Tags,
+		Refs:          t.Refs,
+		BaseAmount:    t.BaseAmount,
+		BaseCurrency:  t.BaseCurrency,
+	}
+}
+
+func (t Transaction) ToAPIMessage() domain.Transaction {
+	return domain.Transaction{
+		ID:        t.TransactionID,
+		UserID:    t.UserID,
+		Account:   t.Account,
+		Party:     t.Party,
+		Direction: t.Direction,
+		Amount:    t.Amount,
+		Currency:  t.Currency,
+		Date:      t.Date,
+		Total:     t.Total,
+		Raw:       t.Raw,
+		Category:  t.Category,
+		Tags:      t.Tags,
+		Refs:      t.Refs,
+
+		BaseAmount:   t.BaseAmount,
+		BaseCurrency: t.BaseCurrency,
+	}
+}
+
+type gormTransactionRepository struct {
+	db *gorm.DB
+}
+
+func (g *gormTransactionRepository) Migration(ctx context.Context) error {
+	if err := g.db.AutoMigrate(&Transaction{}); err != nil {
+		return err
+	}
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		var rows []Transaction
+		if err := db.Unscoped().Select("id", "date").Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, v := range rows {
+			if _, offset := v.Date.Zone(); offset == 0 {
+				continue
+			}
+			if err := db.Unscoped().Where("id = ?", v.ID).UpdateColumn("date", v.Date.UTC()).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (g *gormTransactionRepository) Store(ctx context.Context, item *domain.Transaction) error {
+	if item.UserID == 0 {
+		return errors.New("store/gorm: transaction user not set")
+	}
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		row := DomainTransaction(*item).ToTransaction()
+		return db.Create(&row).Error
+	})
+}
+
+func (g *gormTransactionRepository) Update(ctx context.Context, item *domain.Transaction) error {
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			row, err := g.take(tx, item.UserID, item.ID)
+			if err != nil {
+				return err
+			}
+			update := DomainTransaction(*item).ToTransaction()
+			update.Model = row.Model
+			return tx.Model(&row).Select("*").Omit("CreatedAt", "DeletedAt").Updates(&update).Error
+		})
+	})
+}
+
+func (g *gormTransactionRepository) Delete(ctx context.Context, item *domain.Transaction) error {
+	return g.wrapper(ctx, func(db *gorm.DB) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			row, err := g.take(tx, item.UserID, item.ID)
+			if err != nil {
+				return err
+			}
+			return tx.Delete(&row).Error
+		})
+	})
+}
+
+func (g *gormTransactionRepository) Get(ctx context.Context, userID uint, id string) (domain.Transaction, error) {
+	var row Transaction
+	err := g.wrapper(ctx, func(db *gorm.DB) (err error) {
+		row, err = g.take(db, userID, id)
+		return err
+	})
+	return row.ToAPIMessage(), err
+}
+
+func (g *gormTransactionRepository) List(ctx context.Context, filter domain.TransactionFilter) ([]domain.Transaction, error) {
+	if filter.UserID == 0 {
+		return nil, errors.New("store/gorm: transaction user not set")
+	}
+	var rows []Transaction
+	err := g.wrapper(ctx, func(db *gorm.DB) error {
+		db = db.Where(&Transaction{
+			UserID:    filter.UserID,
+			Account:   filter.Account,
+			Category:  filter.Category,
+			Direction: filter.Direction,
+		})
+		if !filter.From.IsZero() {
+			db = db.Where("date >= ?", filter.From.UTC())
+		}
+		if !filter.To.IsZero() {
+			db = db.Where("date < ?", filter.To.UTC())
+		}
+		if filter.NoBaseCurrency {
+			db = db.Where("base_currency = ?", "")
+		}
+		if filter.Text != "" {
+			text := "%" + strings.ToLower(filter.Text) + "%"
+			db = db.Where("LOWER(party) LIKE ? OR LOWER(raw) LIKE ?", text, text)
+		}
+		if filter.Limit > 0 {
+			db = db.Limit(filter.Limit)
+		}
+		return db.Order("date, id").Find(&rows).Error
+	})
+	var result []domain.Transaction
+	for _, v := range rows {
+		result = append(result, v.ToAPIMessage())
+	}
+	return result, err
+}
+
+func (g *gormTransactionRepository) take(db *gorm.DB, userID uint, id string) (Transaction, error) {
+	row := Transaction{}
+	if id == "" {
+		return row, errors.New("store/gorm: transaction id not set")
+	}
+	err := db.Where("user_id = ? AND transaction_id = ?", userID, id).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return row, ErrTransactionNotFound
+	}
+	return row, err
+}
+
+func (g *gormTransactionRepository) wrapper(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return fn(g.db.WithContext(ctx).Model(&Transaction{}))
+}
+
+func NewGormTransactionRepository(db *gorm.DB) domain.LedgerRepository {
+	return &gormTransactionRepository{
+		db: db,
+	}
+}
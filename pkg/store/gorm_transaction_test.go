@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type GormTransactionRepositoryTestSuite struct {
+	suite.Suite
+	Ctx  context.Context
+	DB   *gorm.DB
+	Repo domain.LedgerRepository
+}
+
+func (suite *GormTransactionRepositoryTestSuite) SetupTest() {
+	var (
+		err error
+	)
+
+	suite.DB, err = gorm.Open(sqlite.Open("file:ledger?mode=memory&cache=shared&_fk=1"), &gorm.Config{})
+	suite.NoError(err)
+
+	suite.DB = suite.DB.Debug()
+
+	suite.Repo = NewGormTransactionRepository(suite.DB)
+
+	suite.Ctx = context.Background()
+
+	suite.NoError(suite.Repo.Migration(suite.Ctx))
+}
+
+func Test_GormTransactionRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(GormTransactionRepositoryTestSuite))
+}
+
+func (suite *GormTransactionRepositoryTestSuite) Test_GormTransactionRepository_Store() {
+	suite.Run("ok", func() {
+		item := domain.Transaction{
+			UserID:    1,
+			Account:   "5098",
+			Party:     "FACEBK",
+			Direction: "c",
+			Amount:    decimal.RequireFromString("1123.33"),
+			Currency:  "AED",
+			Date:      time.Date(2020, 10, 31, 0, 0, 0, 0, time.UTC),
+			Total:     decimal.RequireFromString("13274.59"),
+			Raw:       "AED 1,123.33 is charged on Credit Card ending 5098 from FACEBK on 31/10.",
+		}
+		err := suite.Repo.Store(suite.Ctx, &item)
+		if suite.NoError(err, "Store") {
+			var rows []Transaction
+			suite.NoError(suite.DB.Model(&Transaction{}).Where(&Transaction{UserID: 1}).Find(&rows).Error)
+			if suite.Len(rows, 1) {
+				got := rows[0].ToAPIMessage()
+				suite.Equal(item.Account, got.Account)
+				suite.Equal(item.Party, got.Party)
+				suite.Equal(item.Direction, got.Direction)
+				suite.True(item.Amount.Equal(got.Amount))
+				suite.Equal(item.Currency, got.Currency)
+				suite.True(item.Date.Equal(got.Date))
+				suite.True(item.Total.Equal(got.Total))
+				suite.Equal(item.Raw, got.Raw)
+			}
+		}
+	})
+
+	suite.Run("fail user not set", func() {
+		item := domain.Transaction{
+			Amount: decimal.NewFromInt(1),
+		}
+		err := suite.Repo.Store(suite.Ctx, &item)
+		suite.EqualError(err, "store/gorm: transaction user not set", "Store")
+	})
+}
+
+func (suite *GormTransactionRepositoryTestSuite) Test_GormTransactionRepository_UpdateDelete() {
+	item := domain.Transaction{
+		ID:       "a1b2c3",
+		UserID:   2,
+		Party:    "UBER",
+		Amount:   decimal.RequireFromString("20"),
+		Currency: "AED",
+		Category: "Transport",
+	}
+	suite.NoError(suite.Repo.Store(suite.Ctx, &item))
+
+	get := func() (Transaction, error) {
+		row := Transaction{}
+		err := suite.DB.Model(&Transaction{}).Where(&Transaction{TransactionID: item.ID}).Take(&row).Error
+		return row, err
+	}
+
+	suite.Run("update", func() {
+		update := item
+		update.Party = "UBER EATS"
+		update.Amount = decimal.RequireFromString("35.5")
+		update.Category = ""
+		suite.NoError(suite.Repo.Update(suite.Ctx, &update))
+
+		row, err := get()
+		if suite.NoError(err) {
+			suite.Equal("UBER EATS", row.Party)
+			suite.True(update.Amount.Equal(row.Amount))
+			suite.Equal("", row.Category)
+			suite.False(row.CreatedAt.IsZero())
+		}
+	})
+
+	suite.Run("update other user", func() {
+		update := item
+		update.UserID = 3
+		suite.Error(suite.Repo.Update(suite.Ctx, &update))
+	})
+
+	suite.Run("fail id not set", func() {
+		suite.EqualError(suite.Repo.Update(suite.Ctx, &domain.Transaction{UserID: 2}), "store/gorm: transaction id not set")
+		suite.EqualError(suite.Repo.Delete(suite.Ctx, &domain.Transaction{UserID: 2}), "store/gorm: transaction id not set")
+	})
+
+	suite.Run("delete", func() {
+		suite.NoError(suite.Repo.Delete(suite.Ctx, &item))
+		_, err := get()
+		suite.True(errors.Is(err, gorm.ErrRecordNotFound))
+		suite.True(errors.Is(suite.Repo.Delete(suite.Ctx, &item), ErrTransactionNotFound))
+	})
+}
+
+func (suite *GormTransactionRepositoryTestSuite) Test_GormTransactionRepository_Migration_utc() {
+	row := Transaction{TransactionID: "offset", UserID: 9, Amount: decimal.RequireFromString("-5"),
+		Date: time.Date(2020, 11, 1, 2, 0, 0, 0, time.FixedZone("Dubai", 4*60*60))}
+	suite.Require().NoError(suite.DB.Create(&row).Error)
+	suite.NoError(suite.Repo.Migration(suite.Ctx))
+
+	got, err := suite.Repo.Get(suite.Ctx, 9, "offset")
+	if suite.NoError(err) {
+		_, offset := got.Date.Zone()
+		suite.Zero(offset)
+		suite.True(row.Date.Equal(got.Date))
+	}
+
+	_, err = suite.Repo.Get(suite.Ctx, 0, "offset")
+	suite.True(errors.Is(err, ErrTransactionNotFound), "lookup stays scoped to the user")
+}
+
+func (suite *GormTransactionRepositoryTestSuite) Test_GormTransactionRepository_List_noBaseCurrency() {
+	items := []domain.Transaction{
+		{ID: "converted", UserID: 4, Amount: decimal.RequireFromString("-5"), Currency: "EUR",
+			BaseAmount: decimal.RequireFromString("-6"), BaseCurrency: "USD"},
+		{ID: "failed", UserID: 4, Amount: decimal.RequireFromString("-7"), Currency: "GBP"},
+	}
+	for i := range items {
+		suite.Require().NoError(suite.Repo.Store(suite.Ctx, &items[i]))
+	}
+
+	got, err := suite.Repo.List(suite.Ctx, domain.TransactionFilter{UserID: 4, NoBaseCurrency: true})
+	if suite.NoError(err) && suite.Len(got, 1) {
+		suite.Equal("failed", got[0].ID)
+	}
+}
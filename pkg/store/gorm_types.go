@@ -0,0 +1,85 @@
+package store
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+type StringList []string
+
+func (l *StringList) Scan(value interface{}) (err error) {
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	case nil:
+		*l = nil
+		return nil
+	default:
+		return fmt.Errorf("failed to unmarshal JSON value: %v", value)
+	}
+	return json.Unmarshal(bytes, l)
+}
+
+func (l StringList) Value() (driver.Value, error) {
+	return json.Marshal(l)
+}
+
+func (StringList) GormDataType() string {
+	return "string"
+}
+
+type StringMap map[string]string
+
+func (m *StringMap) Scan(value interface{}) (err error) {
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	case nil:
+		*m = nil
+		return nil
+	default:
+		return fmt.Errorf("failed to unmarshal JSON value: %v", value)
+	}
+	return json.Unmarshal(bytes, m)
+}
+
+func (m StringMap) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+func (StringMap) GormDataType() string {
+	return "string"
+}
+
+type IntList []int
+
+func (l *IntList) Scan(value interface{}) (err error) {
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	case nil:
+		*l = nil
+		return nil
+	default:
+		return fmt.Errorf("failed to unmarshal JSON value: %v", value)
+	}
+	return json.Unmarshal(bytes, l)
+}
+
+func (l IntList) Value() (driver.Value, error) {
+	return json.Marshal(l)
+}
+
+func (IntList) GormDataType() string {
+	return "string"
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ftomza/go-bank-bot/domain"
+)
+
+type TransactionSink struct {
+	Name string
+	Repo domain.TransactionRepository
+	Err  error
+}
+
+type SinkResult struct {
+	Name        string
+	Err         error
+	Unavailable bool
+	Queued      bool
+}
+
+type SinkResults []SinkResult
+
+func (r SinkResults) Failed() SinkResults {
+	var failed SinkResults
+	for _, v := range r {
+		if v.Err != nil {
+			failed = append(failed, v)
+		}
+	}
+	return failed
+}
+
+func (r SinkResults) Saved() bool {
+	for _, v := range r {
+		if v.Err == nil || v.Queued {
+			return true
+		}
+	}
+	return false
+}
+
+func (r SinkResults) Err() error {
+	if failed := r.Failed(); len(failed) > 0 {
+		return &MultiStoreError{Results: failed}
+	}
+	return nil
+}
+
+type MultiStoreError struct {
+	Results SinkResults
+}
+
+func (e *MultiStoreError) Error() string {
+	var items []string
+	for _, v := range e.Results {
+		items = append(items, fmt.Sprintf("%s: %v", v.Name, v.Err))
+	}
+	return "store/multi: " + strings.Join(items, "; ")
+}
+
+type MultiTransactionRepository struct {
+	sinks []TransactionSink
+}
+
+func NewMultiTransactionRepository(sinks ...TransactionSink) *MultiTransactionRepository {
+	return &MultiTransactionRepository{sinks: sinks}
+}
+
+func (m *MultiTransactionRepository) Store(ctx context.Context, item *domain.Transaction) error {
+	return m.StoreResults(ctx, item).Err()
+}
+
+func (m *MultiTransactionRepository) StoreResults(ctx context.Context, item *domain.Transaction) SinkResults {
+	return m.results(func(repo domain.TransactionRepository) error {
+		return repo.Store(ctx, item)
+	})
+}
+
+func (m *MultiTransactionRepository) Update(ctx context.Context, item *domain.Transaction) error {
+	return m.UpdateResults(ctx, item).Err()
+}
+
+func (m *MultiTransactionRepository) UpdateResults(ctx context.Context, item *domain.Transaction) SinkResults {
+	return m.results(func(repo domain.TransactionRepository) error {
+		return repo.Update(ctx, item)
+	})
+}
+
+func (m *MultiTransactionRepository) Delete(ctx context.Context, item *domain.Transaction) error {
+	return m.DeleteResults(ctx, item).Err()
+}
+
+func (m *MultiTransactionRepository) DeleteResults(ctx context.Context, item *domain.Transaction) SinkResults {
+	return m.results(func(repo domain.TransactionRepository) error {
+		return repo.Delete(ctx, item)
+	})
+}
+
+func (m *MultiTransactionRepository) results(fn func(repo domain.TransactionRepository) error) SinkResults {
+	results := make(SinkResults, 0, len(m.sinks))
+	for _, v := range m.sinks {
+		err := v.Err
+		if err == nil && v.Repo == nil {
+			err = errors.New("repository not set")
+		}
+		if err != nil {
+			results = append(results, SinkResult{Name: v.Name, Err: err, Unavailable: true})
+			continue
+		}
+		results = append(results, SinkResult{Name: v.Name, Err: fn(v.Repo)})
+	}
+	return results
+}
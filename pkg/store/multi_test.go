@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryTransactionRepository struct {
+	items []domain.Transaction
+	err   error
+}
+
+func (m *memoryTransactionRepository) Store(_ context.Context, item *domain.Transaction) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.items = append(m.items, *item)
+	return nil
+}
+
+func (m *memoryTransactionRepository) Update(_ context.Context, item *domain.Transaction) error {
+	if m.err != nil {
+		return m.err
+	}
+	for i, v := range m.items {
+		if v.ID == item.ID && v.UserID == item.UserID {
+			m.items[i] = *item
+			return nil
+		}
+	}
+	return ErrTransactionNotFound
+}
+
+func (m *memoryTransactionRepository) Delete(_ context.Context, item *domain.Transaction) error {
+	if m.err != nil {
+		return m.err
+	}
+	for i, v := range m.items {
+		if v.ID == item.ID && v.UserID == item.UserID {
+			m.items = append(m.items[:i], m.items[i+1:]...)
+			return nil
+		}
+	}
+	return ErrTransactionNotFound
+}
+
+func (m *memoryTransactionRepository) Get(_ context.Context, userID uint, id string) (domain.Transaction, error) {
+	if m.err != nil {
+		return domain.Transaction{}, m.err
+	}
+	for _, v := range m.items {
+		if v.ID == id && v.UserID == userID {
+			return v, nil
+		}
+	}
+	return domain.Transaction{}, ErrTransactionNotFound
+}
+
+func (m *memoryTransactionRepository) List(_ context.Context, filter domain.TransactionFilter) ([]domain.Transaction, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var items []domain.Transaction
+	for _, v := range m.items {
+		if v.UserID == filter.UserID {
+			items = append(items, v)
+		}
+	}
+	return filterTransactions(filter, items), nil
+}
+
+func TestMultiTransactionRepository_StoreResults(t *testing.T) {
+	ok := &memoryTransactionRepository{}
+	fail := &memoryTransactionRepository{err: errors.New("quota")}
+
+	repo := NewMultiTransactionRepository(
+		TransactionSink{Name: "google", Repo: fail},
+		TransactionSink{Name: "ledger", Repo: ok},
+		TransactionSink{Name: "broken", Err: errors.New("token not set")},
+	)
+
+	item := &domain.Transaction{Party: "FACEBK"}
+	results := repo.StoreResults(context.Background(), item)
+
+	assert.Equal(t, SinkResults{
+		{Name: "google", Err: errors.New("quota")},
+		{Name: "ledger"},
+		{Name: "broken", Err: errors.New("token not set"), Unavailable: true},
+	}, results)
+	assert.Len(t, ok.items, 1)
+	assert.Len(t, results.Failed(), 2)
+
+	err := repo.Store(context.Background(), item)
+	var multiErr *MultiStoreError
+	if assert.True(t, errors.As(err, &multiErr)) {
+		assert.EqualError(t, err, "store/multi: google: quota; broken: token not set")
+	}
+}
+
+func TestMultiTransactionRepository_Store_ok(t *testing.T) {
+	repo := NewMultiTransactionRepository(
+		TransactionSink{Name: "ledger", Repo: &memoryTransactionRepository{}},
+	)
+	assert.NoError(t, repo.Store(context.Background(), &domain.Transaction{}))
+}
+
+func TestMultiTransactionRepository_UpdateDelete(t *testing.T) {
+	first := &memoryTransactionRepository{}
+	second := &memoryTransactionRepository{}
+	repo := NewMultiTransactionRepository(
+		TransactionSink{Name: "google", Repo: first},
+		TransactionSink{Name: "ledger", Repo: second},
+	)
+
+	item := &domain.Transaction{ID: "1", Party: "FACEBK"}
+	assert.NoError(t, repo.Store(context.Background(), item))
+	second.items = nil
+
+	update := &domain.Transaction{ID: "1", Party: "UBER"}
+	assert.Equal(t, SinkResults{
+		{Name: "google"},
+		{Name: "ledger", Err: ErrTransactionNotFound},
+	}, repo.UpdateResults(context.Background(), update))
+	assert.Equal(t, []domain.Transaction{*update}, first.items)
+
+	assert.Equal(t, SinkResults{
+		{Name: "google"},
+		{Name: "ledger", Err: ErrTransactionNotFound},
+	}, repo.DeleteResults(context.Background(), update))
+	assert.Empty(t, first.items)
+}
@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ftomza/go-bank-bot/domain"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type TransactionRepositoryConformanceTestSuite struct {
+	suite.Suite
+	Ctx     context.Context
+	NewRepo func(t *testing.T) domain.TransactionRepository
+	Repo    domain.TransactionRepository
+}
+
+func (suite *TransactionRepositoryConformanceTestSuite) SetupTest() {
+	suite.Ctx = context.Background()
+	suite.Repo = suite.NewRepo(suite.T())
+}
+
+func Test_TransactionRepositoryConformance(t *testing.T) {
+	ledgerDB := 0
+	repos := map[string]func(t *testing.T) domain.TransactionRepository{
+		"ledger": func(t *testing.T) domain.TransactionRepository {
+			ledgerDB++
+			db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:conformance%d?mode=memory&cache=shared&_fk=1", ledgerDB)), &gorm.Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			repo := NewGormTransactionRepository(db)
+			if err := repo.Migration(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			return repo
+		},
+		"google": func(t *testing.T) domain.TransactionRepository {
+			sheet, repo := newFakeGoogleTransactionRepository(t, "Transactions")
+			sheet.rows = [][]interface{}{{"Account", "Party", "Direction", "Amount", "Currency", "Date"}}
+			return repo
+		},
+		"google monthly": func(t *testing.T) domain.TransactionRepository {
+			_, repo := newFakeGoogleTransactionRepository(t, "Transactions", WithGoogleMonthlyLists())
+			return repo
+		},
+		"memory": func(t *testing.T) domain.TransactionRepository {
+			return &memoryTransactionRepository{}
+		},
+	}
+	for name, fn := range repos {
+		t.Run(name, func(t *testing.T) {
+			suite.Run(t, &TransactionRepositoryConformanceTestSuite{NewRepo: fn})
+		})
+	}
+}
+
+func (suite *TransactionRepositoryConformanceTestSuite) fixtures() []domain.Transaction {
+	date := func(day int) time.Time {
+		return time.Date(2020, 11, day, 10, 0, 0, 0, time.UTC)
+	}
+	items := []domain.Transaction{
+		{ID: "t1", UserID: 1, Account: "5098", Party: "UBER TRIP", Direction: "debit", Amount: decimal.RequireFromString("-20.5"), Currency: "AED", Date: date(1), Category: "Transport", Tags: []string{"taxi"}, Raw: "uber 20.5"},
+		{ID: "t2", UserID: 1, Account: "5098", Party: "CAFE", Direction: "debit", Amount: decimal.RequireFromString("-12"), Currency: "AED", Date: date(3), Category: "Food", Raw: "cafe 12"},
+		{ID: "t3", UserID: 1, Account: "1111", Party: "SALARY", Direction: "credit", Amount: decimal.RequireFromString("1000"), Currency: "AED", Date: date(5), Raw: "salary from ACME"},
+	}
+	for i := range items {
+		suite.Require().NoError(suite.Repo.Store(suite.Ctx, &items[i]))
+	}
+	return items
+}
+
+func (suite *TransactionRepositoryConformanceTestSuite) ids(items []domain.Transaction) []string {
+	var ids []string
+	for _, v := range items {
+		ids = append(ids, v.ID)
+	}
+	return ids
+}
+
+func (suite *TransactionRepositoryConformanceTestSuite) Test_Get() {
+	items := suite.fixtures()
+
+	got, err := suite.Repo.Get(suite.Ctx, 1, "t1")
+	if suite.NoError(err) {
+		suite.Equal(items[0].ID, got.ID)
+		suite.Equal(items[0].UserID, got.UserID)
+		suite.Equal(items[0].Party, got.Party)
+		suite.True(items[0].Amount.Equal(got.Amount))
+		suite.True(items[0].Date.Equal(got.Date))
+		suite.Equal(items[0].Category, got.Category)
+		suite.Equal(items[0].Tags, got.Tags)
+	}
+
+	_, err = suite.Repo.Get(suite.Ctx, 1, "unknown")
+	suite.True(errors.Is(err, ErrTransactionNotFound), "%v", err)
+}
+
+func (suite *TransactionRepositoryConformanceTestSuite) Test_List() {
+	suite.fixtures()
+
+	tests := []struct {
+		name   string
+		filter domain.TransactionFilter
+		want   []string
+	}{
+		{name: "all", filter: domain.TransactionFilter{}, want: []string{"t1", "t2", "t3"}},
+		{name: "date range", filter: domain.TransactionFilter{
+			From: time.Date(2020, 11, 2, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2020, 11, 5, 10, 0, 0, 0, time.UTC),
+		}, want: []string{"t2"}},
+		{name: "account", filter: domain.TransactionFilter{Account: "5098"}, want: []string{"t1", "t2"}},
+		{name: "category", filter: domain.TransactionFilter{Category: "Food"}, want: []string{"t2"}},
+		{name: "direction", filter: domain.TransactionFilter{Direction: "credit"}, want: []string{"t3"}},
+		{name: "text party", filter: domain.TransactionFilter{Text: "uber"}, want: []string{"t1"}},
+		{name: "text raw", filter: domain.TransactionFilter{Text: "acme"}, want: []string{"t3"}},
+		{name: "limit", filter: domain.TransactionFilter{Limit: 2}, want: []string{"t1", "t2"}},
+		{name: "no match", filter: domain.TransactionFilter{Category: "Bills"}},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			tt.filter.UserID = 1
+			items, err := suite.Repo.List(suite.Ctx, tt.filter)
+			if suite.NoError(err) {
+				suite.Equal(tt.want, suite.ids(items))
+			}
+		})
+	}
+}
+
+func (suite *TransactionRepositoryConformanceTestSuite) Test_List_mixedOffsets() {
+	items := []domain.Transaction{
+		{ID: "dubai", UserID: 1, Party: "CAFE", Amount: decimal.RequireFromString("-5"), Currency: "AED",
+			Date: time.Date(2020, 11, 1, 2, 0, 0, 0, time.FixedZone("Dubai", 4*60*60))},
+		{ID: "west", UserID: 1, Party: "SHOP", Amount: decimal.RequireFromString("-7"), Currency: "AED",
+			Date: time.Date(2020, 10, 31, 23, 30, 0, 0, time.FixedZone("West", -2*60*60))},
+	}
+	for i := range items {
+		suite.Require().NoError(suite.Repo.Store(suite.Ctx, &items[i]))
+	}
+
+	list, err := suite.Repo.List(suite.Ctx, domain.TransactionFilter{UserID: 1})
+	if suite.NoError(err) {
+		suite.Equal([]string{"dubai", "west"}, suite.ids(list), "ordered by instant")
+	}
+	list, err = suite.Repo.List(suite.Ctx, domain.TransactionFilter{UserID: 1, From: time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC)})
+	if suite.NoError(err) {
+		suite.Equal([]string{"west"}, suite.ids(list))
+	}
+	list, err = suite.Repo.List(suite.Ctx, domain.TransactionFilter{UserID: 1, To: time.Date(2020, 11, 1, 4, 0, 0, 0, time.FixedZone("Dubai", 4*60*60))})
+	if suite.NoError(err) {
+		suite.Equal([]string{"dubai"}, suite.ids(list))
+	}
+}
+
+func (suite *TransactionRepositoryConformanceTestSuite) Test_Update() {
+	items := suite.fixtures()
+
+	update := items[1]
+	update.Party = "CAFE LATTE"
+	update.Amount = decimal.RequireFromString("-14")
+	update.Category = "Coffee"
+	suite.NoError(suite.Repo.Update(suite.Ctx, &update))
+
+	got, err := suite.Repo.Get(suite.Ctx, 1, "t2")
+	if suite.NoError(err) {
+		suite.Equal("CAFE LATTE", got.Party)
+		suite.True(update.Amount.Equal(got.Amount))
+		suite.Equal("Coffee", got.Category)
+	}
+
+	missing := domain.Transaction{ID: "unknown", UserID: 1}
+	suite.True(errors.Is(suite.Repo.Update(suite.Ctx, &missing), ErrTransactionNotFound))
+}
+
+func (suite *TransactionRepositoryConformanceTestSuite) Test_Delete() {
+	items := suite.fixtures()
+
+	suite.NoError(suite.Repo.Delete(suite.Ctx, &items[0]))
+
+	_, err := suite.Repo.Get(suite.Ctx, 1, "t1")
+	suite.True(errors.Is(err, ErrTransactionNotFound))
+
+	list, err := suite.Repo.List(suite.Ctx, domain.TransactionFilter{UserID: 1})
+	if suite.NoError(err) {
+		suite.Equal([]string{"t2", "t3"}, suite.ids(list))
+	}
+
+	suite.NoError(suite.Repo.Delete(suite.Ctx, &items[2]), "row located after previous delete shifted rows")
+	suite.True(errors.Is(suite.Repo.Delete(suite.Ctx, &items[0]), ErrTransactionNotFound))
+}